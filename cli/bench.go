@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/architmishra-15/go-speed/internal/benchmark"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runBenchCmd implements `speedtest bench`: a plow-style HTTP load test
+// against the server's /ping endpoint.
+func runBenchCmd(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	concurrency := fs.Int("c", 50, "number of concurrent workers")
+	requests := fs.Int("n", 0, "total requests to issue (0 = run for -d instead)")
+	duration := fs.Duration("d", 10*time.Second, "how long to run (ignored if -n is set)")
+	server := fs.String("server", "", "server to benchmark (defaults to the test server)")
+	asJSON := fs.Bool("json", false, "print a final JSON summary instead of the live view")
+	fs.Parse(args)
+
+	target := activeServerBase
+	if *server != "" {
+		target = *server
+	}
+
+	cfg := benchmark.Config{Concurrency: *concurrency, Requests: *requests}
+	if *requests <= 0 {
+		cfg.Duration = *duration
+	}
+	runner := benchmark.NewRunner(target, cfg)
+
+	if *asJSON {
+		runJSON(runner)
+		return
+	}
+	runLive(runner)
+}
+
+// runJSON runs the benchmark headlessly and writes the final Result as JSON
+// to stdout; it skips the live TUI since the two would otherwise fight over
+// stdout.
+func runJSON(runner *benchmark.Runner) {
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintln(os.Stderr, "error encoding result:", err)
+		os.Exit(1)
+	}
+}
+
+// runLive drives the benchmark through a Bubble Tea model that refreshes a
+// live stats table once per tick, plow-style.
+func runLive(runner *benchmark.Runner) {
+	p := tea.NewProgram(newBenchModel(runner))
+	if _, err := p.Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+const benchTickInterval = 200 * time.Millisecond
+
+type benchTickMsg struct{}
+
+type benchDoneMsg struct {
+	result *benchmark.Result
+	err    error
+}
+
+type benchModel struct {
+	runner   *benchmark.Runner
+	snapshot benchmark.Result
+	done     bool
+	err      error
+}
+
+func newBenchModel(runner *benchmark.Runner) benchModel {
+	return benchModel{runner: runner}
+}
+
+func (m benchModel) Init() tea.Cmd {
+	return tea.Batch(m.runCmd(), benchTickCmd())
+}
+
+func benchTickCmd() tea.Cmd {
+	return tea.Tick(benchTickInterval, func(time.Time) tea.Msg { return benchTickMsg{} })
+}
+
+// runCmd kicks off the benchmark in the background; it only resolves once
+// the run completes, while benchTickCmd drives the periodic snapshot
+// refresh in the meantime.
+func (m benchModel) runCmd() tea.Cmd {
+	return func() tea.Msg {
+		result, err := m.runner.Run(context.Background())
+		return benchDoneMsg{result: result, err: err}
+	}
+}
+
+func (m benchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case benchTickMsg:
+		if m.done {
+			return m, nil
+		}
+		m.snapshot = m.runner.Snapshot()
+		return m, benchTickCmd()
+
+	case benchDoneMsg:
+		m.done = true
+		m.err = msg.err
+		if msg.result != nil {
+			m.snapshot = *msg.result
+		}
+		return m, tea.Quit
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		return m, nil
+
+	default:
+		return m, nil
+	}
+}
+
+func (m benchModel) View() string {
+	s := m.snapshot
+	errLine := ""
+	for reason, count := range s.ErrorBreakdown {
+		errLine += fmt.Sprintf("    %s: %d\n", reason, count)
+	}
+
+	status := "running"
+	if m.done {
+		status = "done"
+	}
+	if m.err != nil {
+		status = fmt.Sprintf("error: %v", m.err)
+	}
+
+	return fmt.Sprintf(
+		"Benchmark [%s]  elapsed=%v  in-flight=%d\n\n"+
+			"  Requests:    %d (rps=%.1f)\n"+
+			"  Packet loss: %.2f%%\n"+
+			"  Jitter:      %v\n"+
+			"  Latency:     p50=%v p90=%v p95=%v p99=%v p999=%v\n"+
+			"  Errors:\n%s",
+		status, s.Elapsed.Round(time.Millisecond), s.InFlight,
+		s.Requests, s.RPS,
+		s.PacketLoss*100,
+		s.Jitter,
+		s.P50, s.P90, s.P95, s.P99, s.P999,
+		errLine,
+	)
+}
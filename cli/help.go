@@ -163,6 +163,7 @@ func (m modelmsg) renderHelp() string {
 		lipgloss.JoinHorizontal(lipgloss.Top, commandStyle.Render("version"), commandDescStyle.Render("Show version information")),
 		lipgloss.JoinHorizontal(lipgloss.Top, commandStyle.Render("test"), commandDescStyle.Render("Run network speed test")),
 		lipgloss.JoinHorizontal(lipgloss.Top, commandStyle.Render("servers"), commandDescStyle.Render("List available test servers")),
+		lipgloss.JoinHorizontal(lipgloss.Top, commandStyle.Render("bench"), commandDescStyle.Render("Run an HTTP benchmark with latency percentiles")),
 	}, "\n")
 	sections = append(sections, commandsContent)
 
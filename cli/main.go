@@ -1,43 +1,62 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"sync"
-	"sync/atomic"
 	"time"
 
+	"github.com/architmishra-15/go-speed/internal/measure"
+	"github.com/architmishra-15/go-speed/internal/xfer"
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 const (
 	serverBase = "http://localhost:8080" // speedtest server base URL
-	testSize   = 100 * 1024 * 1024       // 100 MB payload
 	streams    = 4                       // concurrent streams for down/up
 )
 
+// activeServerBase is the base URL the ping/download/upload test commands
+// hit. It defaults to serverBase but `speedtest test --server` can point it
+// at any server, including the auto-selected best one from the registry.
+var activeServerBase = serverBase
+
+// measureStreams and measureDuration configure the measure.Engine used by
+// the download/upload phases of `speedtest test`; 0/0 take the Engine's
+// defaults (auto stream count, 15s). exportPath, if set, writes the raw
+// per-stream sample series from the download phase to that file.
+// retryBase/retryCap/retryAttempts configure the backoff the engine's
+// underlying xfer.Manager applies to each stream's HTTP round trips; 0
+// takes xfer's own defaults.
+var (
+	measureStreams  int
+	measureDuration time.Duration
+	exportPath      string
+	retryBase       time.Duration
+	retryCap        time.Duration
+	retryAttempts   int
+)
+
 var httpClient = &http.Client{Transport: &http.Transport{MaxIdleConns: streams, DisableCompression: true}}
 
 type pingMsg time.Duration
 
-type downloadMsg struct {
-	bytes int64
-	dur   time.Duration
-}
-
-type uploadMsg struct {
-	bytes int64
-	dur   time.Duration
-}
-
-type progressMsg struct {
-	phase string
-	done  int64
-	total int64
+// measureTickMsg drives the progress bar during the download/upload phases.
+// The bar's percentage is elapsed/configured-duration, since these are
+// duration-bound runs with no fixed total to measure bytes against; the
+// instantaneous throughput shown alongside it, though, comes from the
+// engine's real aggregate xfer.Progress feed (measure.Engine.LiveBytes), not
+// from wall-clock time, so a stalled transfer shows up as stalled.
+type measureTickMsg struct{}
+
+// measureResultMsg carries the outcome of one measure.Engine run.
+type measureResultMsg struct {
+	kind   string
+	result *measure.Result
+	err    error
 }
 
 type errorMsg struct {
@@ -50,13 +69,31 @@ type model struct {
 	downloadSpeed float64
 	uploadSpeed   float64
 	progress      progress.Model
+
+	phaseStart time.Time
+
+	// engine is the in-flight download/upload measure.Engine; measureTickMsg
+	// reads its LiveBytes() to show real, not synthetic, throughput.
+	engine        *measure.Engine
+	liveMbps      float64
+	tickBytes     int64
+	tickTimestamp time.Time
+
+	// ctx governs the download/upload measure.Engine runs; cancel is called
+	// on Ctrl-C so an in-flight run's xfer transfers release and stop
+	// retrying instead of running until -duration elapses on its own.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func initialModel() model {
 	prog := progress.New(progress.WithDefaultGradient())
+	ctx, cancel := context.WithCancel(context.Background())
 	return model{
 		phase:    "ping",
 		progress: prog,
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
@@ -70,22 +107,49 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case pingMsg:
 		m.latency = time.Duration(msg)
 		m.phase = "download"
-		return m, downloadTestCmd()
-
-	case downloadMsg:
-		m.downloadSpeed = float64(msg.bytes) / msg.dur.Seconds() / (1024 * 1024)
-		m.phase = "upload"
-		return m, uploadTestCmd()
+		m.phaseStart = time.Now()
+		m.engine = newMeasureEngine()
+		m.tickBytes = 0
+		m.tickTimestamp = m.phaseStart
+		return m, tea.Batch(measureDownloadCmd(m.ctx, m.engine), measureTickCmd())
+
+	case measureTickMsg:
+		if m.phase != "download" && m.phase != "upload" {
+			return m, nil
+		}
+		m.progress.SetPercent(float64(time.Since(m.phaseStart)) / float64(effectiveDuration()))
 
-	case uploadMsg:
-		m.uploadSpeed = float64(msg.bytes) / msg.dur.Seconds() / (1024 * 1024)
-		m.phase = "done"
-		return m, tea.Quit
+		now := time.Now()
+		bytes := m.engine.LiveBytes()
+		if dt := now.Sub(m.tickTimestamp).Seconds(); dt > 0 {
+			m.liveMbps = float64(bytes-m.tickBytes) * 8 / dt / 1_000_000
+		}
+		m.tickBytes = bytes
+		m.tickTimestamp = now
+		return m, measureTickCmd()
 
-	case progressMsg:
-		if msg.total > 0 {
-			percent := float64(msg.done) / float64(msg.total)
-			m.progress.SetPercent(percent)
+	case measureResultMsg:
+		if msg.err != nil {
+			return m, func() tea.Msg { return errorMsg{msg.err} }
+		}
+		switch msg.kind {
+		case "download":
+			m.downloadSpeed = msg.result.AggregateMbps / 8
+			if exportPath != "" {
+				if err := msg.result.ExportSamples(exportPath); err != nil {
+					fmt.Fprintln(os.Stderr, "warning: export failed:", err)
+				}
+			}
+			m.phase = "upload"
+			m.phaseStart = time.Now()
+			m.engine = newMeasureEngine()
+			m.tickBytes = 0
+			m.tickTimestamp = m.phaseStart
+			return m, measureUploadCmd(m.ctx, m.engine)
+		case "upload":
+			m.uploadSpeed = msg.result.AggregateMbps / 8
+			m.phase = "done"
+			return m, tea.Quit
 		}
 		return m, nil
 
@@ -94,6 +158,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.progress.Width = 0
 		return m, tea.Quit
 
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.cancel()
+			return m, tea.Quit
+		}
+		return m, nil
+
 	default:
 		return m, nil
 	}
@@ -105,9 +176,9 @@ func (m model) View() string {
 	case "ping":
 		return fmt.Sprintf("Ping: measuring... %s\n", m.phase)
 	case "download":
-		return fmt.Sprintf("Download: measuring... %s\n%s", m.phase, m.progress.View())
+		return fmt.Sprintf("Download: measuring... %.2f Mbps\n%s", m.liveMbps, m.progress.View())
 	case "upload":
-		return fmt.Sprintf("Upload: measuring... %s\n%s", m.phase, m.progress.View())
+		return fmt.Sprintf("Upload: measuring... %.2f Mbps\n%s", m.liveMbps, m.progress.View())
 	case "done":
 		return fmt.Sprintf("Results:\n  Ping: %v\n  Download: %.2f MB/s\n  Upload:   %.2f MB/s\n", m.latency, m.downloadSpeed, m.uploadSpeed)
 	case "error":
@@ -117,22 +188,11 @@ func (m model) View() string {
 	}
 }
 
-// helper to periodically send progress updates
-func makeProgressCmd(counter *int64, total int64, phase string) tea.Cmd {
-	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
-		done := atomic.LoadInt64(counter)
-		if done >= total {
-			return nil
-		}
-		return progressMsg{phase: phase, done: done, total: total}
-	})
-}
-
 // pingTestCmd runs the ping test by performing an actual GET /ping request
 func pingTestCmd() tea.Cmd {
 	return func() tea.Msg {
 		start := time.Now()
-		resp, err := httpClient.Get(serverBase + "/ping")
+		resp, err := httpClient.Get(activeServerBase + "/ping")
 		if err != nil {
 			return errorMsg{err}
 		}
@@ -142,95 +202,94 @@ func pingTestCmd() tea.Cmd {
 	}
 }
 
-// downloadTestCmd runs the download test by downloading a payload of size `testSize`
-func downloadTestCmd() tea.Cmd {
-	return func() tea.Msg {
-		url := fmt.Sprintf("%s/download?size=%d", serverBase, testSize)
-		start := time.Now()
-		resp, err := httpClient.Get(url)
-		if err != nil {
-			return errorMsg{err}
-		}
-		n, err := io.Copy(io.Discard, resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return errorMsg{err}
-		}
-		dur := time.Since(start)
-		return downloadMsg{bytes: n, dur: dur}
+// newMeasureEngine builds the measure.Engine for the current test run from
+// activeServerBase and the -streams/-duration flags set by runTestCmd.
+func newMeasureEngine() *measure.Engine {
+	return measure.NewEngine(measure.Config{
+		Server:   activeServerBase,
+		Streams:  measureStreams,
+		Duration: measureDuration,
+		Backoff: xfer.BackoffConfig{
+			Base:        retryBase,
+			Cap:         retryCap,
+			MaxAttempts: retryAttempts,
+		},
+	})
+}
+
+// effectiveDuration is the test duration the progress bar ticks against: the
+// configured -duration, or the Engine's own default if unset.
+func effectiveDuration() time.Duration {
+	if measureDuration > 0 {
+		return measureDuration
 	}
+	return 15 * time.Second
 }
 
-// uploadTestCmd runs the upload test by POST-ing a random payload of size `testSize`
-func uploadTestCmd() tea.Cmd {
+// measureDownloadCmd runs a full multi-stream download measurement on engine
+// and reports the aggregated Result; it blocks for the run's whole duration,
+// so measureTickCmd drives the progress bar and live throughput reading (via
+// engine.LiveBytes) in the meantime. ctx is the model's cancellable context,
+// so Ctrl-C aborts the run's xfer transfers instead of letting them run to
+// completion unattended.
+func measureDownloadCmd(ctx context.Context, engine *measure.Engine) tea.Cmd {
 	return func() tea.Msg {
-		var totalBytes int64
-		var wg sync.WaitGroup
-		wg.Add(streams)
-		segSize := testSize / streams
-		counter := int64(0)
-
-		start := time.Now()
+		result, err := engine.MeasureDownload(ctx)
+		return measureResultMsg{kind: "download", result: result, err: err}
+	}
+}
 
-		for i := 0; i < streams; i++ {
-			go func() {
-				defer wg.Done()
-				payload := make([]byte, segSize) // zeros, no randomisation
-				req, err := http.NewRequest("POST", serverBase+"/upload", bytes.NewReader(payload))
-				if err != nil {
-					return
-				}
-				req.ContentLength = int64(segSize)
-				resp, err := httpClient.Do(req)
-				if err == nil {
-					io.Copy(io.Discard, resp.Body)
-					resp.Body.Close()
-					atomic.AddInt64(&totalBytes, int64(segSize))
-					atomic.AddInt64(&counter, int64(segSize))
-				}
-			}()
-		}
+// measureUploadCmd is measureDownloadCmd's upload counterpart.
+func measureUploadCmd(ctx context.Context, engine *measure.Engine) tea.Cmd {
+	return func() tea.Msg {
+		result, err := engine.MeasureUpload(ctx)
+		return measureResultMsg{kind: "upload", result: result, err: err}
+	}
+}
 
-		progressCmd := makeProgressCmd(&counter, int64(testSize), "upload")
+func measureTickCmd() tea.Cmd {
+	return tea.Tick(250*time.Millisecond, func(time.Time) tea.Msg { return measureTickMsg{} })
+}
 
-		wg.Wait()
-		dur := time.Since(start)
+func main() {
+	if len(os.Args) < 2 {
+		runTest()
+		return
+	}
 
-		return tea.Batch(
-			func() tea.Msg { return uploadMsg{bytes: totalBytes, dur: dur} },
-			progressCmd,
-		)()
+	switch os.Args[1] {
+	case "help", "--help", "-h":
+		runInfoModel("help")
+	case "version", "--version", "-v":
+		runInfoModel("version")
+	case "servers":
+		runServersCmd(os.Args[2:])
+	case "test":
+		runTestCmd(os.Args[2:])
+	case "bench":
+		runBenchCmd(os.Args[2:])
+	default:
+		fmt.Printf("Unknown command: %s\n", os.Args[1])
+		fmt.Println("Use 'speedtest help' for usage information")
+		os.Exit(1)
 	}
 }
 
-func main() {
-	if len(os.Args) > 2 {
-		command := os.Args[1]
-		var m model
-		switch command {
-		case "help", "--help", "-h":
-			m = initialModel()
-			m.phase = "help"
-		case "version", "--version", "-v":
-			m = initialModel()
-			m.phase = "version"
-		default:
-			fmt.Printf("Unknown command: %s\n", command)
-			fmt.Println("Use 'speedtest help' for usage information")
-			os.Exit(1)
-		}
-
-		p := tea.NewProgram(m)
-		if _, err := p.Run(); err != nil {
-			fmt.Printf("Error: %v", err)
-			os.Exit(1)
-		}
+// runInfoModel renders the help or version screen.
+func runInfoModel(mode string) {
+	m := initialModelMsg(mode)
+	p := tea.NewProgram(m)
+	if _, err := p.Run(); err != nil {
+		fmt.Printf("Error: %v", err)
+		os.Exit(1)
 	}
+}
 
+// runTest runs the ping/download/upload test against activeServerBase.
+func runTest() {
 	p := tea.NewProgram(initialModel())
-	if err := p.Start(); err != nil {
+	if _, err := p.Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
-
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/architmishra-15/go-speed/internal/servers"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runServersCmd implements `speedtest servers`: it ranks every known server
+// by latency and renders the result as a table in ascending-latency order.
+// The table cannot be re-sorted from the view; any key press exits.
+func runServersCmd(args []string) {
+	fs := flag.NewFlagSet("servers", flag.ExitOnError)
+	fetchURL := fs.String("fetch", "", "HTTPS URL to fetch an additional server list from")
+	discover := fs.Bool("discover", false, "discover servers on the local network")
+	fs.Parse(args)
+
+	reg := servers.NewRegistry()
+	if *fetchURL != "" {
+		if err := reg.Fetch(context.Background(), *fetchURL); err != nil {
+			fmt.Fprintln(os.Stderr, "warning: fetch failed:", err)
+		}
+	}
+	if *discover {
+		found, err := servers.DiscoverLocal(2 * time.Second)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "warning: discovery failed:", err)
+		} else {
+			reg.Add(found...)
+		}
+	}
+
+	ranked, err := reg.RankByLatency(context.Background(), 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	p := tea.NewProgram(newServersModel(ranked))
+	if _, err := p.Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+// runTestCmd implements `speedtest test [--server host|auto]`.
+func runTestCmd(args []string) {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	serverFlag := fs.String("server", "", `server to test against (a host URL, or "auto" to pick the fastest known server)`)
+	streamsFlag := fs.Int("streams", 0, "number of parallel streams (0 = auto: min(16, 2*NumCPU))")
+	durationFlag := fs.Duration("duration", 0, "how long to measure each direction (0 = 15s default)")
+	exportFlag := fs.String("export", "", "write the download phase's raw per-stream samples to this JSON file")
+	latFlag := fs.Float64("lat", 0, `client latitude, used to break "--server auto" latency ties by distance`)
+	lonFlag := fs.Float64("lon", 0, `client longitude, used to break "--server auto" latency ties by distance`)
+	retryBaseFlag := fs.Duration("retry-base", 0, "base delay before the first retry of a failed stream request (0 = 500ms default)")
+	retryCapFlag := fs.Duration("retry-cap", 0, "maximum backoff delay between retries (0 = 30s default)")
+	retriesFlag := fs.Int("retries", 0, "max attempts per stream request, including the first (0 = 5 default)")
+	fs.Parse(args)
+
+	measureStreams = *streamsFlag
+	measureDuration = *durationFlag
+	exportPath = *exportFlag
+	retryBase = *retryBaseFlag
+	retryCap = *retryCapFlag
+	retryAttempts = *retriesFlag
+	hasCoords := *latFlag != 0 || *lonFlag != 0
+
+	switch *serverFlag {
+	case "":
+		// keep the default activeServerBase
+	case "auto":
+		reg := servers.NewRegistry()
+		best, err := reg.SelectBest(context.Background(), *latFlag, *lonFlag, hasCoords)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error selecting server:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Selected server: %s (%s)\n", best.Name, best.Host)
+		activeServerBase = best.Host
+	default:
+		activeServerBase = *serverFlag
+	}
+
+	runTest()
+}
+
+// serversModel renders the ranked server table and quits on any key press.
+type serversModel struct {
+	table table.Model
+}
+
+func newServersModel(ranked []servers.Ranked) serversModel {
+	columns := []table.Column{
+		{Title: "Name", Width: 16},
+		{Title: "Region", Width: 12},
+		{Title: "Host", Width: 28},
+		{Title: "Latency", Width: 10},
+	}
+
+	rows := make([]table.Row, 0, len(ranked))
+	for _, r := range ranked {
+		latency := "unreachable"
+		if r.Reachable {
+			latency = r.MedianRTT.Round(time.Millisecond).String()
+		}
+		rows = append(rows, table.Row{r.Server.Name, r.Server.Region, r.Server.Host, latency})
+	}
+
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(len(rows)+1),
+	)
+	return serversModel{table: t}
+}
+
+func (m serversModel) Init() tea.Cmd { return nil }
+
+func (m serversModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m, tea.Quit
+	default:
+		_ = msg
+		return m, nil
+	}
+}
+
+func (m serversModel) View() string {
+	return "Available test servers (ranked by latency):\n\n" + m.table.View() + "\n\nPress any key to exit.\n"
+}
@@ -0,0 +1,90 @@
+package benchmark
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// histBase and histMax define the bucket layout: bucket i covers RTTs up to
+// histBase^(i+1) nanoseconds, giving roughly 2% relative resolution per
+// bucket (HdrHistogram-style) all the way out to histMax without needing a
+// dynamically-sized structure.
+const (
+	histBase = 1.02
+	histMax  = 60 * time.Second
+)
+
+// Histogram is a fixed, log-bucketed latency histogram. Record and Quantile
+// are both O(numBuckets), which is a compile-time constant (~1250 buckets
+// for the default range), so in practice both are effectively O(1)
+// regardless of sample count.
+type Histogram struct {
+	buckets []int64 // counts, indexed by bucketIndex
+	count   int64
+}
+
+// NewHistogram returns an empty Histogram sized to cover [0, histMax].
+func NewHistogram() *Histogram {
+	n := bucketIndex(histMax) + 1
+	return &Histogram{buckets: make([]int64, n)}
+}
+
+// bucketIndex maps a duration to its bucket, clamping non-positive durations
+// into bucket 0.
+func bucketIndex(d time.Duration) int {
+	ns := float64(d.Nanoseconds())
+	if ns < 1 {
+		ns = 1
+	}
+	idx := int(math.Log(ns) / math.Log(histBase))
+	if idx < 0 {
+		idx = 0
+	}
+	return idx
+}
+
+// bucketUpperBound returns the largest duration that falls into bucket idx.
+func bucketUpperBound(idx int) time.Duration {
+	return time.Duration(math.Pow(histBase, float64(idx+1)))
+}
+
+// Record adds a single latency sample. Samples above histMax are folded into
+// the last bucket.
+func (h *Histogram) Record(d time.Duration) {
+	idx := bucketIndex(d)
+	if idx >= len(h.buckets) {
+		idx = len(h.buckets) - 1
+	}
+	atomic.AddInt64(&h.buckets[idx], 1)
+	atomic.AddInt64(&h.count, 1)
+}
+
+// Count returns the total number of recorded samples.
+func (h *Histogram) Count() int64 {
+	return atomic.LoadInt64(&h.count)
+}
+
+// Quantile returns the upper bound of the bucket holding the sample at the
+// given quantile (0 < q <= 1), e.g. Quantile(0.99) is p99, so the result is
+// accurate to the ~2% bucket resolution. It returns 0 if no samples have
+// been recorded.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	total := h.Count()
+	if total == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(q * float64(total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var cumulative int64
+	for i := range h.buckets {
+		cumulative += atomic.LoadInt64(&h.buckets[i])
+		if cumulative >= target {
+			return bucketUpperBound(i)
+		}
+	}
+	return bucketUpperBound(len(h.buckets) - 1)
+}
@@ -0,0 +1,94 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramRecordAndCount(t *testing.T) {
+	h := NewHistogram()
+	if got := h.Count(); got != 0 {
+		t.Fatalf("Count() on empty histogram = %d, want 0", got)
+	}
+	for i := 0; i < 5; i++ {
+		h.Record(10 * time.Millisecond)
+	}
+	if got := h.Count(); got != 5 {
+		t.Fatalf("Count() = %d, want 5", got)
+	}
+}
+
+func TestHistogramQuantileEmpty(t *testing.T) {
+	h := NewHistogram()
+	if got := h.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile(0.5) on empty histogram = %v, want 0", got)
+	}
+}
+
+func TestHistogramQuantileSingleValue(t *testing.T) {
+	h := NewHistogram()
+	h.Record(10 * time.Millisecond)
+	got := h.Quantile(0.99)
+	if got < 10*time.Millisecond || got > 11*time.Millisecond {
+		t.Errorf("Quantile(0.99) with one 10ms sample = %v, want within [10ms, 11ms] bucket resolution", got)
+	}
+}
+
+// TestHistogramQuantileOrdering checks that percentiles are monotonically
+// non-decreasing and land in the expected relative order across a spread of
+// samples, exercising the log-bucket math end to end.
+func TestHistogramQuantileOrdering(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	p50 := h.Quantile(0.50)
+	p90 := h.Quantile(0.90)
+	p99 := h.Quantile(0.99)
+
+	if !(p50 <= p90 && p90 <= p99) {
+		t.Fatalf("percentiles not ordered: p50=%v p90=%v p99=%v", p50, p90, p99)
+	}
+	// With 100 uniformly spread 1..100ms samples, p50 should land near 50ms
+	// and p99 near 99-100ms, within the ~2% per-bucket resolution.
+	if p50 < 45*time.Millisecond || p50 > 55*time.Millisecond {
+		t.Errorf("p50 = %v, want ~50ms", p50)
+	}
+	if p99 < 95*time.Millisecond || p99 > 105*time.Millisecond {
+		t.Errorf("p99 = %v, want ~99-100ms", p99)
+	}
+}
+
+func TestHistogramRecordAboveMaxFoldsIntoLastBucket(t *testing.T) {
+	h := NewHistogram()
+	h.Record(histMax * 10) // far above the configured range
+	if got := h.Count(); got != 1 {
+		t.Fatalf("Count() = %d, want 1", got)
+	}
+	got := h.Quantile(1.0)
+	want := bucketUpperBound(len(h.buckets) - 1)
+	if got != want {
+		t.Errorf("Quantile(1.0) after an over-range sample = %v, want last bucket's upper bound %v", got, want)
+	}
+}
+
+func TestBucketIndexMonotonic(t *testing.T) {
+	prev := bucketIndex(0)
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, 10 * time.Millisecond, time.Second, 30 * time.Second} {
+		idx := bucketIndex(d)
+		if idx < prev {
+			t.Errorf("bucketIndex(%v) = %d, expected >= previous bucket index %d", d, idx, prev)
+		}
+		prev = idx
+	}
+}
+
+func TestBucketIndexClampsNonPositive(t *testing.T) {
+	if got := bucketIndex(0); got != 0 {
+		t.Errorf("bucketIndex(0) = %d, want 0", got)
+	}
+	if got := bucketIndex(-5 * time.Second); got != 0 {
+		t.Errorf("bucketIndex(negative) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,247 @@
+// Package benchmark implements a plow-style HTTP load generator for
+// characterizing a speedtest server's /ping endpoint: concurrent workers,
+// HDR-style latency percentiles, jitter, and packet loss.
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config controls how a benchmark run is driven.
+type Config struct {
+	Concurrency int           // number of concurrent workers
+	Requests    int           // stop after this many requests; 0 = unbounded
+	Duration    time.Duration // stop after this long; 0 = unbounded
+
+	// Timeout bounds each individual request.
+	Timeout time.Duration
+}
+
+// Result is a point-in-time (or final) snapshot of a benchmark run.
+type Result struct {
+	Elapsed    time.Duration
+	InFlight   int64
+	Requests   int64
+	Successes  int64
+	Errors     int64
+	Timeouts   int64
+	PacketLoss float64 // Errors/Requests (Errors already includes Timeouts)
+	RPS        float64
+	Jitter     time.Duration // mean absolute deviation of consecutive RTTs
+
+	P50, P90, P95, P99, P999 time.Duration
+
+	ErrorBreakdown map[string]int64 `json:"error_breakdown,omitempty"`
+}
+
+// Runner drives a benchmark against a single target URL's /ping endpoint.
+type Runner struct {
+	target string
+	cfg    Config
+	client *http.Client
+	hist   *Histogram
+
+	start time.Time
+
+	inFlight  int64
+	requests  int64
+	successes int64
+	errors    int64
+	timeouts  int64
+
+	jitterMu    sync.Mutex
+	lastRTT     time.Duration
+	haveLastRTT bool
+	jitterSum   time.Duration
+	jitterCount int64
+
+	breakdownMu sync.Mutex
+	breakdown   map[string]int64
+}
+
+// NewRunner builds a Runner targeting target (a server base URL, e.g.
+// "http://localhost:8080").
+func NewRunner(target string, cfg Config) *Runner {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 50
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &Runner{
+		target:    target,
+		cfg:       cfg,
+		client:    &http.Client{Timeout: cfg.Timeout},
+		hist:      NewHistogram(),
+		breakdown: make(map[string]int64),
+	}
+}
+
+// Run drives the benchmark to completion, blocking until the request/
+// duration bound is hit or ctx is cancelled, and returns the final Result.
+func (r *Runner) Run(ctx context.Context) (*Result, error) {
+	if r.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Duration)
+		defer cancel()
+	}
+
+	r.start = time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(r.cfg.Concurrency)
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			r.worker(ctx)
+		}()
+	}
+	wg.Wait()
+
+	result := r.Snapshot()
+	return &result, nil
+}
+
+// worker fires requests in a loop until ctx is done or the request budget
+// (Config.Requests) is exhausted.
+func (r *Runner) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if r.cfg.Requests > 0 {
+			if atomic.AddInt64(&r.requests, 1) > int64(r.cfg.Requests) {
+				atomic.AddInt64(&r.requests, -1)
+				return
+			}
+		} else {
+			atomic.AddInt64(&r.requests, 1)
+		}
+
+		r.fire(ctx)
+	}
+}
+
+// fire performs a single /ping request and records its outcome.
+func (r *Runner) fire(ctx context.Context) {
+	atomic.AddInt64(&r.inFlight, 1)
+	defer atomic.AddInt64(&r.inFlight, -1)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.target+"/ping", nil)
+	if err != nil {
+		r.recordFailure("request-build", false)
+		return
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	rtt := time.Since(start)
+	if err != nil {
+		timedOut := false
+		if netErr, ok := err.(net.Error); ok {
+			timedOut = netErr.Timeout()
+		}
+		r.recordFailure("network_error", timedOut)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		r.recordFailure(fmt.Sprintf("http_%d", resp.StatusCode), false)
+		return
+	}
+
+	atomic.AddInt64(&r.successes, 1)
+	r.hist.Record(rtt)
+	r.recordJitter(rtt)
+}
+
+func (r *Runner) recordFailure(reason string, timeout bool) {
+	atomic.AddInt64(&r.errors, 1)
+	if timeout {
+		atomic.AddInt64(&r.timeouts, 1)
+	}
+	r.breakdownMu.Lock()
+	r.breakdown[reason]++
+	r.breakdownMu.Unlock()
+}
+
+// recordJitter folds rtt into the running mean absolute deviation of
+// consecutive RTTs, serialized so "consecutive" has a well-defined meaning
+// across concurrent workers.
+func (r *Runner) recordJitter(rtt time.Duration) {
+	r.jitterMu.Lock()
+	defer r.jitterMu.Unlock()
+	if r.haveLastRTT {
+		delta := rtt - r.lastRTT
+		if delta < 0 {
+			delta = -delta
+		}
+		r.jitterSum += delta
+		r.jitterCount++
+	}
+	r.lastRTT = rtt
+	r.haveLastRTT = true
+}
+
+// Snapshot returns the run's current stats without stopping it, suitable for
+// driving a live-updating view.
+func (r *Runner) Snapshot() Result {
+	requests := atomic.LoadInt64(&r.requests)
+	successes := atomic.LoadInt64(&r.successes)
+	errors := atomic.LoadInt64(&r.errors)
+	timeouts := atomic.LoadInt64(&r.timeouts)
+	elapsed := time.Since(r.start)
+
+	var packetLoss float64
+	if requests > 0 {
+		packetLoss = float64(errors) / float64(requests)
+	}
+	var rps float64
+	if elapsed > 0 {
+		rps = float64(requests) / elapsed.Seconds()
+	}
+
+	r.jitterMu.Lock()
+	var jitter time.Duration
+	if r.jitterCount > 0 {
+		jitter = r.jitterSum / time.Duration(r.jitterCount)
+	}
+	r.jitterMu.Unlock()
+
+	r.breakdownMu.Lock()
+	breakdown := make(map[string]int64, len(r.breakdown))
+	for k, v := range r.breakdown {
+		breakdown[k] = v
+	}
+	r.breakdownMu.Unlock()
+
+	return Result{
+		Elapsed:        elapsed,
+		InFlight:       atomic.LoadInt64(&r.inFlight),
+		Requests:       requests,
+		Successes:      successes,
+		Errors:         errors,
+		Timeouts:       timeouts,
+		PacketLoss:     packetLoss,
+		RPS:            rps,
+		Jitter:         jitter,
+		P50:            r.hist.Quantile(0.50),
+		P90:            r.hist.Quantile(0.90),
+		P95:            r.hist.Quantile(0.95),
+		P99:            r.hist.Quantile(0.99),
+		P999:           r.hist.Quantile(0.999),
+		ErrorBreakdown: breakdown,
+	}
+}
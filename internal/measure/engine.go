@@ -0,0 +1,253 @@
+// Package measure implements a multi-stream, warm-up-aware throughput
+// measurement engine: it runs N parallel streams for a fixed wall-clock
+// duration, discards slow-start and cool-down samples, and reports
+// aggregate and per-stream statistics, matching how tools like Ookla and
+// nuttcp characterize a link instead of dividing total bytes by elapsed
+// time.
+package measure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/architmishra-15/go-speed/internal/xfer"
+)
+
+// Config controls a measurement run.
+type Config struct {
+	Server string // server base URL, e.g. "http://localhost:8080"
+
+	// Streams is the number of parallel HTTP streams. 0 means auto:
+	// min(16, 2*NumCPU).
+	Streams int
+	// Duration is the total wall-clock time the run spends transferring
+	// data, including the warm-up and cool-down windows that get discarded
+	// afterward. Defaults to 15s.
+	Duration time.Duration
+	// WarmUp is how much of the start of each stream's samples to discard
+	// (TCP slow-start, connection setup). Defaults to 2s.
+	WarmUp time.Duration
+	// CoolDown is how much of the end of each stream's samples to discard.
+	// Defaults to 500ms.
+	CoolDown time.Duration
+	// ChunkBytes is the size requested per HTTP round trip; streams issue a
+	// new request in a loop until Duration elapses. Defaults to 64MB.
+	ChunkBytes int
+	// Backoff controls the retry-with-backoff behavior of the underlying
+	// xfer.Manager for each stream's HTTP round trips. A zero-value
+	// BackoffConfig takes xfer's own defaults (500ms base, 30s cap, 5
+	// attempts).
+	Backoff xfer.BackoffConfig
+}
+
+func (c Config) withDefaults() Config {
+	if c.Streams <= 0 {
+		c.Streams = autoStreams()
+	}
+	if c.Duration <= 0 {
+		c.Duration = 15 * time.Second
+	}
+	if c.WarmUp <= 0 {
+		c.WarmUp = 2 * time.Second
+	}
+	if c.CoolDown <= 0 {
+		c.CoolDown = 500 * time.Millisecond
+	}
+	if c.ChunkBytes <= 0 {
+		c.ChunkBytes = 64 * 1024 * 1024
+	}
+	return c
+}
+
+func autoStreams() int {
+	n := 2 * runtime.NumCPU()
+	if n > 16 {
+		n = 16
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Engine drives one multi-stream measurement run. Each stream's HTTP
+// round trips go through an xfer.Manager so transient failures (timeouts,
+// unexpected EOF, 5xx) are retried with backoff instead of aborting the
+// stream outright.
+type Engine struct {
+	cfg  Config
+	xfer *xfer.Manager
+
+	// liveBytes is the cumulative bytes transferred across every stream so
+	// far in the current run, updated as xfer.Progress events arrive. It
+	// lets a caller drive a real-time progress/throughput indicator off
+	// actual transfer activity instead of wall-clock elapsed time alone.
+	liveBytes int64
+}
+
+// NewEngine returns an Engine. Zero-value Config fields take the documented
+// defaults.
+func NewEngine(cfg Config) *Engine {
+	cfg = cfg.withDefaults()
+	return &Engine{
+		cfg:  cfg,
+		xfer: xfer.NewManager(cfg.Backoff),
+	}
+}
+
+// streamState is the per-stream accumulator drain writes into as a stream's
+// xfer.Transfer reports progress.
+type streamState struct {
+	ring  *ringBuffer
+	start time.Time
+	cum   int64
+}
+
+// zeroReader is an upload payload source: it yields n zero bytes, matching
+// the zero-filled (non-randomized) payloads the rest of the client uses.
+type zeroReader struct{ remaining int64 }
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.remaining <= 0 {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if n > z.remaining {
+		n = z.remaining
+	}
+	for i := int64(0); i < n; i++ {
+		p[i] = 0
+	}
+	z.remaining -= n
+	return int(n), nil
+}
+
+// MeasureDownload runs the configured number of parallel download streams
+// for Config.Duration and returns the aggregated Result.
+func (e *Engine) MeasureDownload(ctx context.Context) (*Result, error) {
+	return e.run(ctx, "download")
+}
+
+// MeasureUpload runs the configured number of parallel upload streams for
+// Config.Duration and returns the aggregated Result.
+func (e *Engine) MeasureUpload(ctx context.Context) (*Result, error) {
+	return e.run(ctx, "upload")
+}
+
+// LiveBytes returns the cumulative bytes transferred across every stream so
+// far in the current (or most recently started) run. Safe to call
+// concurrently with MeasureDownload/MeasureUpload to drive a live progress
+// indicator.
+func (e *Engine) LiveBytes() int64 {
+	return atomic.LoadInt64(&e.liveBytes)
+}
+
+func (e *Engine) run(ctx context.Context, kind string) (*Result, error) {
+	runCtx, cancel := context.WithTimeout(ctx, e.cfg.Duration)
+	defer cancel()
+
+	atomic.StoreInt64(&e.liveBytes, 0)
+	start := time.Now()
+	states := make([]*streamState, e.cfg.Streams)
+	var wg sync.WaitGroup
+	wg.Add(e.cfg.Streams)
+	for i := 0; i < e.cfg.Streams; i++ {
+		st := &streamState{ring: newRingBuffer(capacityFor(e.cfg.Duration)), start: start}
+		states[i] = st
+		go func(idx int) {
+			defer wg.Done()
+			e.runStream(runCtx, kind, idx, st)
+		}(i)
+	}
+	wg.Wait()
+
+	perStream := make([][]sample, len(states))
+	for i, st := range states {
+		perStream[i] = st.ring.snapshot()
+	}
+	return aggregate(perStream, e.cfg), nil
+}
+
+// runStream repeatedly issues requests on behalf of one stream until runCtx
+// is done. Each request is a distinct xfer.Manager key (streamIdx+iteration),
+// since the manager's dedup is meant for independent callers sharing work,
+// not for the engine's own parallel streams; what each stream gets from
+// xfer is the retry-with-backoff loop and a cancellable handle.
+func (e *Engine) runStream(ctx context.Context, kind string, idx int, st *streamState) {
+	for iter := 0; ; iter++ {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		var err error
+		if kind == "download" {
+			err = e.downloadOnce(ctx, idx, iter, st)
+		} else {
+			err = e.uploadOnce(ctx, idx, iter, st)
+		}
+		if err != nil && ctx.Err() == nil {
+			// Transient failure mid-run (e.g. a reset connection); brief
+			// pause so a persistently broken server doesn't spin the loop.
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+func (e *Engine) downloadOnce(ctx context.Context, idx, iter int, st *streamState) error {
+	url := fmt.Sprintf("%s/download?size=%d", e.cfg.Server, e.cfg.ChunkBytes)
+	key := fmt.Sprintf("measure-download-%d-%d", idx, iter)
+	t := e.xfer.Download(key, xfer.Spec{URL: url, Total: int64(e.cfg.ChunkBytes)})
+	return e.drain(ctx, t, st)
+}
+
+func (e *Engine) uploadOnce(ctx context.Context, idx, iter int, st *streamState) error {
+	url := e.cfg.Server + "/upload"
+	chunkBytes := int64(e.cfg.ChunkBytes)
+	key := fmt.Sprintf("measure-upload-%d-%d", idx, iter)
+	spec := xfer.Spec{
+		URL:   url,
+		Total: chunkBytes,
+		Body: func() (io.ReadCloser, error) {
+			return io.NopCloser(&zeroReader{remaining: chunkBytes}), nil
+		},
+	}
+	t := e.xfer.Upload(key, spec)
+	return e.drain(ctx, t, st)
+}
+
+// drain consumes a transfer's progress events into st's ring buffer and
+// e.liveBytes until the transfer finishes or ctx is cancelled (e.g. the
+// run's overall Duration elapsed, or the user hit Ctrl-C), releasing the
+// transfer either way so its retry loop is cancelled promptly rather than
+// running to completion unattended.
+func (e *Engine) drain(ctx context.Context, t *xfer.Transfer, st *streamState) error {
+	defer t.Release()
+	base := st.cum
+	var lastReported int64
+	report := func(bytes int64) {
+		atomic.AddInt64(&e.liveBytes, bytes-lastReported)
+		lastReported = bytes
+	}
+	for {
+		select {
+		case p := <-t.Progress():
+			report(p.Bytes)
+			st.cum = base + p.Bytes
+			st.ring.push(sample{elapsed: time.Since(st.start), bytes: st.cum})
+		case <-t.Done():
+			final := t.LastProgress()
+			report(final.Bytes)
+			st.cum = base + final.Bytes
+			return t.Err()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
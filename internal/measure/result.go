@@ -0,0 +1,203 @@
+package measure
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// saturationBucket is the time slice used to evaluate whether every stream
+// was simultaneously saturated.
+const saturationBucket = 50 * time.Millisecond
+
+// StreamStat is one stream's aggregate throughput over the measurement
+// window.
+type StreamStat struct {
+	Index int     `json:"index"`
+	Mbps  float64 `json:"mbps"`
+}
+
+// Result is the outcome of one MeasureDownload/MeasureUpload run.
+type Result struct {
+	Streams            []StreamStat  `json:"streams"`
+	AggregateMbps      float64       `json:"aggregate_mbps"`
+	StdDevMbps         float64       `json:"stddev_mbps"`
+	SaturationFraction float64       `json:"saturation_fraction"`
+	WindowDuration     time.Duration `json:"window_duration"`
+
+	// samples holds each stream's raw (elapsed, cumulative bytes) series
+	// for offline analysis via ExportSamples; it's unexported, so it is
+	// left out of the Result's own JSON encoding to keep that small.
+	samples [][]sample
+}
+
+// aggregate turns the raw per-stream sample series into a Result, discarding
+// the warm-up and cool-down portions of the run per cfg.
+func aggregate(perStream [][]sample, cfg Config) *Result {
+	windowStart := cfg.WarmUp
+	windowEnd := cfg.Duration - cfg.CoolDown
+	if windowEnd <= windowStart {
+		windowStart = 0
+		windowEnd = cfg.Duration
+	}
+	windowDur := windowEnd - windowStart
+
+	streamStats := make([]StreamStat, len(perStream))
+	mbpsValues := make([]float64, len(perStream))
+	peaks := make([]float64, len(perStream))
+	var totalBytes int64
+
+	for i, samples := range perStream {
+		bytesInWindow := cumAt(samples, windowEnd) - cumAt(samples, windowStart)
+		if bytesInWindow < 0 {
+			bytesInWindow = 0
+		}
+		mbps := mbpsOf(bytesInWindow, windowDur)
+		streamStats[i] = StreamStat{Index: i, Mbps: mbps}
+		mbpsValues[i] = mbps
+		totalBytes += bytesInWindow
+		peaks[i] = peakRate(samples, windowStart, windowEnd)
+	}
+
+	return &Result{
+		Streams:            streamStats,
+		AggregateMbps:      mbpsOf(totalBytes, windowDur),
+		StdDevMbps:         stddevOf(mbpsValues),
+		SaturationFraction: saturationFraction(perStream, peaks, windowStart, windowEnd),
+		WindowDuration:     windowDur,
+		samples:            perStream,
+	}
+}
+
+func mbpsOf(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / d.Seconds() / 1_000_000
+}
+
+// cumAt returns the cumulative byte count at time t, i.e. the value carried
+// by the last sample at or before t (0 if t precedes every sample).
+func cumAt(samples []sample, t time.Duration) int64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	idx := sort.Search(len(samples), func(i int) bool { return samples[i].elapsed > t })
+	if idx == 0 {
+		return 0
+	}
+	return samples[idx-1].bytes
+}
+
+// peakRate returns the highest instantaneous bytes/sec observed between any
+// two consecutive samples whose interval overlaps [windowStart, windowEnd],
+// including pairs that straddle either edge of the window.
+func peakRate(samples []sample, windowStart, windowEnd time.Duration) float64 {
+	var peak float64
+	for i := 1; i < len(samples); i++ {
+		if samples[i].elapsed < windowStart || samples[i-1].elapsed > windowEnd {
+			continue
+		}
+		dt := (samples[i].elapsed - samples[i-1].elapsed).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		rate := float64(samples[i].bytes-samples[i-1].bytes) / dt
+		if rate > peak {
+			peak = rate
+		}
+	}
+	return peak
+}
+
+// saturationFraction buckets the window into saturationBucket-sized slices
+// and reports the fraction of slices where every stream's throughput was at
+// or above 90% of that stream's own peak rate.
+func saturationFraction(perStream [][]sample, peaks []float64, windowStart, windowEnd time.Duration) float64 {
+	windowDur := windowEnd - windowStart
+	if windowDur <= 0 || len(perStream) == 0 {
+		return 0
+	}
+
+	buckets := int(windowDur / saturationBucket)
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	saturated := 0
+	for b := 0; b < buckets; b++ {
+		bStart := windowStart + time.Duration(b)*saturationBucket
+		bEnd := bStart + saturationBucket
+
+		all := true
+		for i, samples := range perStream {
+			if peaks[i] <= 0 {
+				all = false
+				break
+			}
+			rate := mbpsOf(cumAt(samples, bEnd)-cumAt(samples, bStart), saturationBucket)
+			peakMbps := mbpsOf(int64(peaks[i]*saturationBucket.Seconds()), saturationBucket)
+			if rate < 0.9*peakMbps {
+				all = false
+				break
+			}
+		}
+		if all {
+			saturated++
+		}
+	}
+	return float64(saturated) / float64(buckets)
+}
+
+func stddevOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		d := v - mean
+		variance += d * d
+	}
+	variance /= float64(len(values))
+	return math.Sqrt(variance)
+}
+
+// exportedSample and exportedStream are the shapes written by ExportSamples;
+// kept separate from the internal sample type so the JSON export format
+// doesn't depend on the in-memory layout.
+type exportedSample struct {
+	ElapsedMs int64 `json:"elapsed_ms"`
+	Bytes     int64 `json:"bytes"`
+}
+
+type exportedStream struct {
+	Index   int              `json:"index"`
+	Samples []exportedSample `json:"samples"`
+}
+
+// ExportSamples writes every stream's raw (elapsed, cumulative bytes) series
+// to path as JSON, for offline analysis (e.g. plotting throughput ramp-up).
+func (r *Result) ExportSamples(path string) error {
+	streams := make([]exportedStream, len(r.samples))
+	for i, samples := range r.samples {
+		es := make([]exportedSample, len(samples))
+		for j, s := range samples {
+			es[j] = exportedSample{ElapsedMs: s.elapsed.Milliseconds(), Bytes: s.bytes}
+		}
+		streams[i] = exportedStream{Index: i, Samples: es}
+	}
+
+	data, err := json.MarshalIndent(streams, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
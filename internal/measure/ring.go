@@ -0,0 +1,74 @@
+package measure
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// sampleGranularity is how often a stream records a sample: once per this
+// many bytes transferred. It's sized to match xfer's progressChunkSize, the
+// cadence at which the underlying transfer reports progress, so every
+// progress event becomes exactly one sample.
+const sampleGranularity = 64 * 1024
+
+// sample is one timestamped, cumulative-bytes reading for a single stream.
+type sample struct {
+	elapsed time.Duration // time since the stream's measurement started
+	bytes   int64         // cumulative bytes transferred by this stream so far
+}
+
+// ringBuffer is a fixed-capacity circular buffer of samples. Each ringBuffer
+// has exactly one writer (the stream goroutine that owns it), so pushes need
+// no locking; the only atomic is the position counter, which also lets
+// snapshot be called safely from the aggregating goroutine after the writer
+// has stopped.
+type ringBuffer struct {
+	buf []sample
+	pos int64
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	if capacity < 16 {
+		capacity = 16
+	}
+	return &ringBuffer{buf: make([]sample, capacity)}
+}
+
+// push records s, overwriting the oldest entry once the buffer wraps.
+func (r *ringBuffer) push(s sample) {
+	i := atomic.AddInt64(&r.pos, 1) - 1
+	r.buf[i%int64(len(r.buf))] = s
+}
+
+// snapshot returns the recorded samples in chronological order. If the
+// buffer wrapped, only the most recent len(buf) samples are available.
+func (r *ringBuffer) snapshot() []sample {
+	n := atomic.LoadInt64(&r.pos)
+	if n == 0 {
+		return nil
+	}
+	if n <= int64(len(r.buf)) {
+		out := make([]sample, n)
+		copy(out, r.buf[:n])
+		return out
+	}
+
+	// Wrapped: the oldest surviving sample is at index n%len(buf).
+	out := make([]sample, len(r.buf))
+	start := n % int64(len(r.buf))
+	copy(out, r.buf[start:])
+	copy(out[int64(len(r.buf))-start:], r.buf[:start])
+	return out
+}
+
+// capacityFor sizes a ring buffer generously for a stream expected to run
+// for duration at up to ~10Gbps, sampled every sampleGranularity bytes.
+func capacityFor(duration time.Duration) int {
+	const assumedPeakBps int64 = 10_000_000_000 / 8 // 10 Gbps in bytes/sec
+	ticksPerSec := assumedPeakBps / sampleGranularity
+	n := int(ticksPerSec * (int64(duration/time.Second) + 2))
+	if n < 4096 {
+		n = 4096
+	}
+	return n
+}
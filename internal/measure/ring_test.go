@@ -0,0 +1,69 @@
+package measure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRingBufferSnapshotBeforeWrap(t *testing.T) {
+	r := newRingBuffer(4)
+	want := []sample{
+		{elapsed: 1 * time.Millisecond, bytes: 10},
+		{elapsed: 2 * time.Millisecond, bytes: 20},
+		{elapsed: 3 * time.Millisecond, bytes: 30},
+	}
+	for _, s := range want {
+		r.push(s)
+	}
+
+	got := r.snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("snapshot() len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("snapshot()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRingBufferSnapshotAfterWrap(t *testing.T) {
+	// Built directly rather than via newRingBuffer, which raises any
+	// capacity below 16 to 16 and so would never wrap here.
+	r := &ringBuffer{buf: make([]sample, 4)}
+	// Push 6 samples into a capacity-4 buffer: only the last 4 should
+	// survive, in chronological order.
+	for i := int64(1); i <= 6; i++ {
+		r.push(sample{elapsed: time.Duration(i) * time.Millisecond, bytes: i * 10})
+	}
+
+	got := r.snapshot()
+	want := []sample{
+		{elapsed: 3 * time.Millisecond, bytes: 30},
+		{elapsed: 4 * time.Millisecond, bytes: 40},
+		{elapsed: 5 * time.Millisecond, bytes: 50},
+		{elapsed: 6 * time.Millisecond, bytes: 60},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("snapshot() len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("snapshot()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRingBufferSnapshotEmpty(t *testing.T) {
+	r := newRingBuffer(4)
+	if got := r.snapshot(); got != nil {
+		t.Errorf("snapshot() on empty buffer = %+v, want nil", got)
+	}
+}
+
+func TestNewRingBufferEnforcesMinimumCapacity(t *testing.T) {
+	r := newRingBuffer(1)
+	if len(r.buf) != 16 {
+		t.Errorf("newRingBuffer(1) capacity = %d, want 16 (minimum)", len(r.buf))
+	}
+}
@@ -0,0 +1,70 @@
+package servers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// discoveryPort is the UDP port speedtest servers listen on for LAN
+// discovery announcements. It is deliberately separate from the HTTP port so
+// a server can announce itself without binding privileged ports.
+const discoveryPort = 8181
+
+// discoveryMulticastAddr is the multicast group used for announcements, in
+// the same family as mDNS (224.0.0.251) but on our own port/payload so we
+// don't need a full DNS-SD implementation to interoperate with it.
+const discoveryMulticastAddr = "224.0.0.251"
+
+// discoveryQuery is broadcast to solicit announcements from servers.
+const discoveryQuery = "SPEEDTEST_DISCOVER"
+
+// DiscoverLocal sends a multicast query on the LAN and collects announcement
+// replies for the given duration. Servers wishing to be discoverable should
+// listen on discoveryMulticastAddr:discoveryPort and reply to
+// discoveryQuery with a JSON-encoded Server.
+//
+// This is a lightweight, purpose-built discovery protocol rather than full
+// mDNS/DNS-SD, which is more than this tool needs.
+func DiscoverLocal(timeout time.Duration) ([]Server, error) {
+	addr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", discoveryMulticastAddr, discoveryPort))
+	if err != nil {
+		return nil, fmt.Errorf("servers: resolve multicast addr: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("servers: open discovery socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP([]byte(discoveryQuery), addr); err != nil {
+		return nil, fmt.Errorf("servers: send discovery query: %w", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("servers: set read deadline: %w", err)
+	}
+
+	var found []Server
+	seen := make(map[string]bool)
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// deadline exceeded (or any other read error) ends the sweep
+			break
+		}
+		var s Server
+		if err := json.Unmarshal(buf[:n], &s); err != nil {
+			continue
+		}
+		if s.Host == "" || seen[s.Host] {
+			continue
+		}
+		seen[s.Host] = true
+		found = append(found, s)
+	}
+	return found, nil
+}
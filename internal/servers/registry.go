@@ -0,0 +1,290 @@
+// Package servers implements the test-server registry: a small catalogue of
+// speedtest backends that can be bundled, fetched over HTTPS, or discovered
+// on the local network, along with latency-based ranking so the client can
+// pick the best one automatically.
+package servers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Server describes a single speedtest backend.
+type Server struct {
+	Name    string  `json:"name"`
+	Host    string  `json:"host"` // base URL, e.g. "http://speed.example.com:8080"
+	Region  string  `json:"region"`
+	Sponsor string  `json:"sponsor,omitempty"`
+	Lat     float64 `json:"lat,omitempty"`
+	Lon     float64 `json:"lon,omitempty"`
+}
+
+// Ranked pairs a Server with the latency measurement gathered by RankByLatency.
+type Ranked struct {
+	Server     Server
+	MedianRTT  time.Duration
+	Reachable  bool
+	ProbeError error
+}
+
+// defaultServers is the bundled fallback list used when no remote source has
+// been fetched and local discovery finds nothing.
+var defaultServers = []Server{
+	{Name: "local", Host: "http://localhost:8080", Region: "local", Sponsor: "bundled"},
+}
+
+// Registry holds the known set of test servers and knows how to refresh,
+// probe, and rank them.
+type Registry struct {
+	mu      sync.RWMutex
+	servers []Server
+	client  *http.Client
+
+	// ProbesPerServer controls how many /ping round trips RankByLatency
+	// fires per candidate before taking the median. Defaults to 3.
+	ProbesPerServer int
+	// ProbeTimeout bounds each individual /ping probe.
+	ProbeTimeout time.Duration
+}
+
+// NewRegistry returns a Registry pre-populated with the bundled server list.
+func NewRegistry() *Registry {
+	servers := make([]Server, len(defaultServers))
+	copy(servers, defaultServers)
+	return &Registry{
+		servers:         servers,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		ProbesPerServer: 3,
+		ProbeTimeout:    3 * time.Second,
+	}
+}
+
+// Servers returns a copy of the currently known server list.
+func (r *Registry) Servers() []Server {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Server, len(r.servers))
+	copy(out, r.servers)
+	return out
+}
+
+// Add merges additional servers into the registry, skipping ones whose host
+// is already known.
+func (r *Registry) Add(candidates ...Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool, len(r.servers))
+	for _, s := range r.servers {
+		seen[s.Host] = true
+	}
+	for _, c := range candidates {
+		if !seen[c.Host] {
+			r.servers = append(r.servers, c)
+			seen[c.Host] = true
+		}
+	}
+}
+
+// Fetch retrieves a server list from a remote HTTPS endpoint and replaces the
+// registry's contents with it. The endpoint must return a JSON array of
+// Server objects.
+func (r *Registry) Fetch(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("servers: build fetch request: %w", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("servers: fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("servers: fetch %s: unexpected status %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("servers: read response: %w", err)
+	}
+
+	var fetched []Server
+	if err := json.Unmarshal(body, &fetched); err != nil {
+		return fmt.Errorf("servers: decode response: %w", err)
+	}
+	if len(fetched) == 0 {
+		return fmt.Errorf("servers: fetch %s: empty server list", url)
+	}
+
+	r.mu.Lock()
+	r.servers = fetched
+	r.mu.Unlock()
+	return nil
+}
+
+// RankByLatency probes every known server concurrently, firing
+// ProbesPerServer /ping requests each, and returns the servers sorted by
+// ascending median round-trip time. Unreachable servers sort last and carry
+// their probe error. If topN > 0, only the best topN entries are returned.
+func (r *Registry) RankByLatency(ctx context.Context, topN int) ([]Ranked, error) {
+	servers := r.Servers()
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("servers: registry is empty")
+	}
+
+	probes := r.ProbesPerServer
+	if probes <= 0 {
+		probes = 3
+	}
+	timeout := r.ProbeTimeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	results := make([]Ranked, len(servers))
+	var wg sync.WaitGroup
+	wg.Add(len(servers))
+	for i, s := range servers {
+		go func(i int, s Server) {
+			defer wg.Done()
+			results[i] = r.probeServer(ctx, s, probes, timeout)
+		}(i, s)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		ri, rj := results[i], results[j]
+		if ri.Reachable != rj.Reachable {
+			return ri.Reachable // reachable servers sort first
+		}
+		return ri.MedianRTT < rj.MedianRTT
+	})
+
+	if topN > 0 && topN < len(results) {
+		results = results[:topN]
+	}
+	return results, nil
+}
+
+// probeServer fires `probes` sequential /ping requests against s and returns
+// the median RTT. Probes run sequentially per server (concurrency happens
+// across servers in RankByLatency) so a single slow server can't starve the
+// shared client's connection pool.
+func (r *Registry) probeServer(ctx context.Context, s Server, probes int, timeout time.Duration) Ranked {
+	rtts := make([]time.Duration, 0, probes)
+	var lastErr error
+	for i := 0; i < probes; i++ {
+		reqCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, s.Host+"/ping", nil)
+		if err != nil {
+			cancel()
+			lastErr = err
+			continue
+		}
+		resp, err := r.client.Do(req)
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("unexpected status %s", resp.Status)
+			continue
+		}
+		rtts = append(rtts, time.Since(start))
+	}
+
+	if len(rtts) == 0 {
+		return Ranked{Server: s, Reachable: false, ProbeError: lastErr}
+	}
+	return Ranked{Server: s, Reachable: true, MedianRTT: median(rtts)}
+}
+
+func median(d []time.Duration) time.Duration {
+	sorted := make([]time.Duration, len(d))
+	copy(sorted, d)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// SelectBest ranks every known server and returns the single best candidate.
+// Ties within tieThreshold of the lowest median RTT are broken by geographic
+// distance to (clientLat, clientLon) using the Haversine formula; pass
+// hasCoords=false to skip distance tie-breaking when the client's location
+// isn't known.
+func (r *Registry) SelectBest(ctx context.Context, clientLat, clientLon float64, hasCoords bool) (*Server, error) {
+	const tieThreshold = 5 * time.Millisecond
+
+	ranked, err := r.RankByLatency(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var reachable []Ranked
+	for _, rk := range ranked {
+		if rk.Reachable {
+			reachable = append(reachable, rk)
+		}
+	}
+	if len(reachable) == 0 {
+		return nil, fmt.Errorf("servers: no reachable server found")
+	}
+
+	best := pickWithinTieWindow(reachable, clientLat, clientLon, hasCoords, tieThreshold)
+	return &best.Server, nil
+}
+
+// pickWithinTieWindow returns the best candidate from reachable (already
+// sorted ascending by MedianRTT): the lowest-RTT entry, unless hasCoords is
+// true, in which case every entry within tieThreshold of the lowest RTT is
+// also considered and the closest one by Haversine distance wins. The tie
+// window is always anchored to reachable[0]'s RTT, not to whichever entry is
+// currently winning, so a chain of servers each individually within
+// tieThreshold of its neighbor can't walk the window arbitrarily far past
+// the true fastest server.
+func pickWithinTieWindow(reachable []Ranked, clientLat, clientLon float64, hasCoords bool, tieThreshold time.Duration) Ranked {
+	best := reachable[0]
+	if !hasCoords {
+		return best
+	}
+
+	lowestRTT := reachable[0].MedianRTT
+	bestDist := haversineKM(clientLat, clientLon, best.Server.Lat, best.Server.Lon)
+	for _, rk := range reachable[1:] {
+		if rk.MedianRTT-lowestRTT > tieThreshold {
+			break // no longer within the tie window; results are sorted by RTT
+		}
+		dist := haversineKM(clientLat, clientLon, rk.Server.Lat, rk.Server.Lon)
+		if dist < bestDist {
+			best, bestDist = rk, dist
+		}
+	}
+	return best
+}
+
+// haversineKM returns the great-circle distance in kilometres between two
+// lat/lon points.
+func haversineKM(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKM = 6371.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadiusKM * c
+}
@@ -0,0 +1,89 @@
+package servers
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMedian(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []time.Duration
+		want time.Duration
+	}{
+		{"odd", []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond}, 20 * time.Millisecond},
+		{"even", []time.Duration{10 * time.Millisecond, 40 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}, 25 * time.Millisecond},
+		{"single", []time.Duration{15 * time.Millisecond}, 15 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := median(tc.in); got != tc.want {
+				t.Errorf("median(%v) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHaversineKM(t *testing.T) {
+	// Same point: zero distance.
+	if d := haversineKM(40.7128, -74.0060, 40.7128, -74.0060); d > 1e-6 {
+		t.Errorf("haversineKM(same point) = %v, want ~0", d)
+	}
+
+	// NYC to London is a well-known ~5570km great-circle distance.
+	got := haversineKM(40.7128, -74.0060, 51.5074, -0.1278)
+	want := 5570.0
+	if math.Abs(got-want) > 50 {
+		t.Errorf("haversineKM(NYC, London) = %v, want ~%v", got, want)
+	}
+}
+
+func TestPickWithinTieWindowNoCoords(t *testing.T) {
+	reachable := []Ranked{
+		{Server: Server{Name: "a"}, MedianRTT: 10 * time.Millisecond},
+		{Server: Server{Name: "b"}, MedianRTT: 12 * time.Millisecond},
+	}
+	got := pickWithinTieWindow(reachable, 0, 0, false, 5*time.Millisecond)
+	if got.Server.Name != "a" {
+		t.Errorf("pickWithinTieWindow(hasCoords=false) = %q, want %q", got.Server.Name, "a")
+	}
+}
+
+// TestPickWithinTieWindowAnchoredToLowestRTT guards against the tie window
+// sliding forward as `best` is reassigned: a chain of servers each within
+// tieThreshold of their neighbor, but the last one more than tieThreshold
+// from the true lowest RTT, must not be selected even if it's the closest by
+// distance.
+func TestPickWithinTieWindowAnchoredToLowestRTT(t *testing.T) {
+	const tieThreshold = 5 * time.Millisecond
+	clientLat, clientLon := 0.0, 0.0
+
+	reachable := []Ranked{
+		{Server: Server{Name: "fastest", Lat: 10, Lon: 10}, MedianRTT: 10 * time.Millisecond},
+		{Server: Server{Name: "within-window", Lat: 9, Lon: 9}, MedianRTT: 14 * time.Millisecond},
+		// 18ms is >5ms from the true lowest (10ms) but within 5ms of the
+		// previous entry (14ms); it must NOT win even though it's closest.
+		{Server: Server{Name: "outside-window-but-closest", Lat: 0.1, Lon: 0.1}, MedianRTT: 18 * time.Millisecond},
+	}
+
+	got := pickWithinTieWindow(reachable, clientLat, clientLon, true, tieThreshold)
+	if got.Server.Name != "within-window" {
+		t.Errorf("pickWithinTieWindow() = %q, want %q (the closest server actually within the tie window of the lowest RTT)", got.Server.Name, "within-window")
+	}
+}
+
+func TestPickWithinTieWindowPicksClosestWithinWindow(t *testing.T) {
+	const tieThreshold = 5 * time.Millisecond
+	clientLat, clientLon := 0.0, 0.0
+
+	reachable := []Ranked{
+		{Server: Server{Name: "fastest", Lat: 10, Lon: 10}, MedianRTT: 10 * time.Millisecond},
+		{Server: Server{Name: "closer", Lat: 1, Lon: 1}, MedianRTT: 12 * time.Millisecond},
+	}
+
+	got := pickWithinTieWindow(reachable, clientLat, clientLon, true, tieThreshold)
+	if got.Server.Name != "closer" {
+		t.Errorf("pickWithinTieWindow() = %q, want %q", got.Server.Name, "closer")
+	}
+}
@@ -0,0 +1,45 @@
+package xfer
+
+import (
+	"math/rand"
+	"time"
+)
+
+// BackoffConfig controls the exponential backoff applied between retry
+// attempts.
+type BackoffConfig struct {
+	Base        time.Duration // delay before the first retry
+	Cap         time.Duration // maximum delay between retries
+	MaxAttempts int           // total attempts, including the first
+}
+
+// defaultBackoff mirrors typical HTTP client retry defaults: quick enough to
+// recover from a blip, capped so a flaky link doesn't stall the whole test.
+var defaultBackoff = BackoffConfig{
+	Base:        500 * time.Millisecond,
+	Cap:         30 * time.Second,
+	MaxAttempts: 5,
+}
+
+func (c BackoffConfig) withDefaults() BackoffConfig {
+	if c.Base <= 0 {
+		c.Base = defaultBackoff.Base
+	}
+	if c.Cap <= 0 {
+		c.Cap = defaultBackoff.Cap
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultBackoff.MaxAttempts
+	}
+	return c
+}
+
+// delay returns the backoff delay before retry attempt n (n=1 is the delay
+// before the second attempt), as exponential backoff with full jitter.
+func (c BackoffConfig) delay(n int) time.Duration {
+	d := c.Base << uint(n-1)
+	if d <= 0 || d > c.Cap { // guard against overflow from the shift
+		d = c.Cap
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
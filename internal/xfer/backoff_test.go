@@ -0,0 +1,72 @@
+package xfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBackoffConfigWithDefaults(t *testing.T) {
+	c := BackoffConfig{}.withDefaults()
+	if c.Base != defaultBackoff.Base || c.Cap != defaultBackoff.Cap || c.MaxAttempts != defaultBackoff.MaxAttempts {
+		t.Fatalf("withDefaults() = %+v, want %+v", c, defaultBackoff)
+	}
+
+	custom := BackoffConfig{Base: time.Second, Cap: time.Minute, MaxAttempts: 2}.withDefaults()
+	if custom.Base != time.Second || custom.Cap != time.Minute || custom.MaxAttempts != 2 {
+		t.Fatalf("withDefaults() overwrote explicit fields: %+v", custom)
+	}
+}
+
+func TestBackoffConfigDelayStaysWithinCap(t *testing.T) {
+	c := BackoffConfig{Base: 500 * time.Millisecond, Cap: 30 * time.Second, MaxAttempts: 5}
+	for n := 1; n <= 20; n++ {
+		d := c.delay(n)
+		if d < 0 || d > c.Cap {
+			t.Fatalf("delay(%d) = %v, want within [0, %v]", n, d, c.Cap)
+		}
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+var _ net.Error = timeoutErr{}
+
+type permanentNetErr struct{}
+
+func (permanentNetErr) Error() string   { return "connection refused" }
+func (permanentNetErr) Timeout() bool   { return false }
+func (permanentNetErr) Temporary() bool { return false }
+
+var _ net.Error = permanentNetErr{}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"net timeout", timeoutErr{}, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"wrapped unexpected EOF", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"5xx server error", serverError{errors.New("server error 503")}, true},
+		{"connection refused (non-timeout net.Error)", permanentNetErr{}, false},
+		{"retry disabled (4xx)", retryDisabled{errors.New("unexpected status 404")}, false},
+		{"generic permanent error", errors.New("malformed URL"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.err); got != tc.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package xfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// progressChunkSize is how often (in bytes read/written) an in-progress
+// transfer reports a progress update.
+const progressChunkSize = 64 * 1024
+
+// Manager dedupes concurrent requests for the same transfer and drives each
+// one through a retry-with-backoff loop.
+type Manager struct {
+	mu        sync.Mutex
+	transfers map[string]*sharedTransfer
+
+	client  *http.Client
+	backoff BackoffConfig
+}
+
+// NewManager returns a Manager. A zero-value BackoffConfig uses the package
+// defaults (500ms base, 30s cap, 5 attempts).
+func NewManager(backoff BackoffConfig) *Manager {
+	return &Manager{
+		transfers: make(map[string]*sharedTransfer),
+		client:    &http.Client{},
+		backoff:   backoff.withDefaults(),
+	}
+}
+
+// Download starts (or joins) a download transfer for key. Callers sharing a
+// key receive progress events from the same underlying HTTP request.
+func (m *Manager) Download(key string, spec Spec) *Transfer {
+	return m.join(key, "download", spec)
+}
+
+// Upload starts (or joins) an upload transfer for key.
+func (m *Manager) Upload(key string, spec Spec) *Transfer {
+	return m.join(key, "upload", spec)
+}
+
+func (m *Manager) join(key, kind string, spec Spec) *Transfer {
+	m.mu.Lock()
+	st, ok := m.transfers[key]
+	if !ok {
+		st = newSharedTransfer(m, key, kind, spec)
+		m.transfers[key] = st
+		go m.run(st)
+	}
+	t := st.watch()
+	m.mu.Unlock()
+	return t
+}
+
+// evict removes st from the registry, but only if it's still the transfer
+// registered under key (a new transfer may already have replaced it).
+func (m *Manager) evict(key string, st *sharedTransfer) {
+	m.mu.Lock()
+	if m.transfers[key] == st {
+		delete(m.transfers, key)
+	}
+	m.mu.Unlock()
+}
+
+// run drives st's retry loop to completion and records the terminal result.
+func (m *Manager) run(st *sharedTransfer) {
+	var err error
+attempts:
+	for attempt := 1; attempt <= m.backoff.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-st.ctx.Done():
+				err = st.ctx.Err()
+				break attempts
+			case <-time.After(m.backoff.delay(attempt - 1)):
+			}
+			st.publish(Progress{Bytes: 0, Total: st.spec.Total}) // reset UI for the retry
+		}
+
+		if st.kind == "upload" {
+			err = m.attemptUpload(st)
+		} else {
+			err = m.attemptDownload(st)
+		}
+
+		if ctxErr := st.ctx.Err(); ctxErr != nil {
+			err = ctxErr
+			break attempts
+		}
+		if err == nil || !isRetryable(err) {
+			break attempts
+		}
+	}
+
+	st.finish(err)
+}
+
+// attemptDownload performs a single GET attempt, reporting progress as the
+// body streams in.
+func (m *Manager) attemptDownload(st *sharedTransfer) error {
+	req, err := http.NewRequestWithContext(st.ctx, http.MethodGet, st.spec.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return serverError{fmt.Errorf("xfer: download %s: server error %s", st.spec.URL, resp.Status)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return retryDisabled{fmt.Errorf("xfer: download %s: unexpected status %s", st.spec.URL, resp.Status)}
+	}
+
+	pw := newProgressWriter(st)
+	_, err = io.Copy(pw, resp.Body)
+	st.publish(Progress{Bytes: pw.written, Total: st.spec.Total})
+	return err
+}
+
+// attemptUpload performs a single POST attempt, replaying the body from the
+// start via spec.Body().
+func (m *Manager) attemptUpload(st *sharedTransfer) error {
+	body, err := st.spec.Body()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	pr := newProgressReader(st, body)
+	req, err := http.NewRequestWithContext(st.ctx, http.MethodPost, st.spec.URL, pr)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = st.spec.Total
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 500 {
+		return serverError{fmt.Errorf("xfer: upload %s: server error %s", st.spec.URL, resp.Status)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return retryDisabled{fmt.Errorf("xfer: upload %s: unexpected status %s", st.spec.URL, resp.Status)}
+	}
+	return nil
+}
+
+// retryDisabled wraps an error that should never be retried (e.g. a 4xx
+// client error), distinguishing it from transient failures.
+type retryDisabled struct{ error }
+
+// serverError wraps a 5xx response, marking it as a failure that's still
+// worth retrying.
+type serverError struct{ error }
+
+// isRetryable reports whether err represents a transient failure worth
+// retrying: network timeouts, unexpected EOF, or a 5xx server error. Every
+// other error — DNS failures, connection refused, malformed URLs, 4xx
+// responses — is treated as permanent and surfaces immediately.
+func isRetryable(err error) bool {
+	var disabled retryDisabled
+	if errors.As(err, &disabled) {
+		return false
+	}
+	var srvErr serverError
+	if errors.As(err, &srvErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	return false
+}
+
+// progressWriter counts bytes written through it and reports progress to a
+// sharedTransfer every progressChunkSize bytes.
+type progressWriter struct {
+	st      *sharedTransfer
+	written int64
+	pending int64
+}
+
+func newProgressWriter(st *sharedTransfer) *progressWriter {
+	return &progressWriter{st: st}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	w.written += int64(n)
+	w.pending += int64(n)
+	if w.pending >= progressChunkSize {
+		w.pending = 0
+		w.st.publish(Progress{Bytes: w.written, Total: w.st.spec.Total})
+	}
+	return n, nil
+}
+
+// progressReader wraps an upload body, reporting progress as it's read.
+type progressReader struct {
+	st      *sharedTransfer
+	r       io.Reader
+	read    int64
+	pending int64
+}
+
+func newProgressReader(st *sharedTransfer, r io.Reader) *progressReader {
+	return &progressReader{st: st, r: r}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	r.pending += int64(n)
+	if r.pending >= progressChunkSize || (err == io.EOF && r.pending > 0) {
+		r.pending = 0
+		r.st.publish(Progress{Bytes: r.read, Total: r.st.spec.Total})
+	}
+	return n, err
+}
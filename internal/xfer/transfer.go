@@ -0,0 +1,178 @@
+// Package xfer provides a transfer manager for the client's download/upload
+// commands, modeled on Docker's content-transfer manager: callers requesting
+// the same transfer share one underlying HTTP exchange, every attempt is
+// wrapped in retry-with-backoff, and each watcher gets its own progress feed
+// plus a clean way to cancel.
+package xfer
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Progress is a single progress update for a transfer.
+type Progress struct {
+	Bytes int64 // bytes transferred so far
+	Total int64 // expected total, 0 if unknown
+}
+
+// Spec describes the work a transfer should perform.
+type Spec struct {
+	URL string
+	// Total is the expected transfer size in bytes (used for progress
+	// percentages), e.g. the download's ?size= or the upload body's length.
+	Total int64
+	// Body, for uploads, returns a fresh reader over the payload. It is
+	// called once per attempt so retries can replay the body from byte 0.
+	Body func() (io.ReadCloser, error)
+}
+
+// Transfer is a per-caller handle onto a (possibly shared) transfer.
+type Transfer struct {
+	shared *sharedTransfer
+	events chan Progress
+
+	releaseOnce sync.Once
+}
+
+// Progress returns a channel of progress updates. It is closed when this
+// handle is released, not when the transfer finishes; use Done() to wait for
+// completion and LastProgress() for the final state.
+func (t *Transfer) Progress() <-chan Progress { return t.events }
+
+// Done returns a channel that's closed when the underlying transfer
+// completes, whether or not this caller has released its watch.
+func (t *Transfer) Done() <-chan struct{} { return t.shared.done }
+
+// Err returns the transfer's terminal error, if any. It's only meaningful
+// after Done() has fired.
+func (t *Transfer) Err() error {
+	t.shared.mu.Lock()
+	defer t.shared.mu.Unlock()
+	return t.shared.err
+}
+
+// LastProgress returns the most recent progress snapshot, including after
+// Done() has fired (at which point it reflects the final byte count).
+func (t *Transfer) LastProgress() Progress {
+	t.shared.mu.Lock()
+	defer t.shared.mu.Unlock()
+	return t.shared.latest
+}
+
+// Release drops this caller's interest in the transfer. Once every watcher
+// has released, the underlying transfer is cancelled (if still running) and
+// evicted from the manager so a later request for the same key starts
+// fresh.
+func (t *Transfer) Release() {
+	t.releaseOnce.Do(func() {
+		t.shared.unwatch(t)
+	})
+}
+
+// sharedTransfer is the single in-flight transfer backing one or more
+// Transfer handles.
+type sharedTransfer struct {
+	mgr  *Manager
+	key  string
+	kind string // "download" or "upload"; selects the attempt Manager.run makes
+	spec Spec
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	refCount int
+	watchers map[*Transfer]bool
+	latest   Progress
+	err      error
+}
+
+func newSharedTransfer(mgr *Manager, key, kind string, spec Spec) *sharedTransfer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sharedTransfer{
+		mgr:      mgr,
+		key:      key,
+		kind:     kind,
+		spec:     spec,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		watchers: make(map[*Transfer]bool),
+	}
+}
+
+// watch registers a new Transfer handle for this shared transfer and returns
+// it. Must be called with mgr.mu held.
+func (s *sharedTransfer) watch() *Transfer {
+	t := &Transfer{shared: s, events: make(chan Progress, 1)}
+	s.mu.Lock()
+	s.refCount++
+	s.watchers[t] = true
+	latest := s.latest
+	s.mu.Unlock()
+	t.send(latest)
+	return t
+}
+
+// unwatch drops a Transfer handle. When the last watcher drops off, the
+// shared transfer is cancelled and evicted from the manager.
+func (s *sharedTransfer) unwatch(t *Transfer) {
+	s.mu.Lock()
+	delete(s.watchers, t)
+	s.refCount--
+	remaining := s.refCount
+	s.mu.Unlock()
+	close(t.events)
+
+	if remaining <= 0 {
+		s.cancel()
+		s.mgr.evict(s.key, s)
+	}
+}
+
+// publish fans out a progress update to every current watcher, without
+// blocking on slow readers: each watcher channel is buffered to depth 1 and
+// always holds only the most recent update.
+func (s *sharedTransfer) publish(p Progress) {
+	s.mu.Lock()
+	s.latest = p
+	watchers := make([]*Transfer, 0, len(s.watchers))
+	for t := range s.watchers {
+		watchers = append(watchers, t)
+	}
+	s.mu.Unlock()
+
+	for _, t := range watchers {
+		t.send(p)
+	}
+}
+
+// send delivers p to t's channel, dropping a stale buffered update first so
+// the channel always reflects the latest progress.
+func (t *Transfer) send(p Progress) {
+	select {
+	case t.events <- p:
+	default:
+		select {
+		case <-t.events:
+		default:
+		}
+		select {
+		case t.events <- p:
+		default:
+		}
+	}
+}
+
+// finish records the terminal error (nil on success), closes done, and
+// leaves the final progress in place for any watcher that reads after the
+// fact via LastProgress() and Err().
+func (s *sharedTransfer) finish(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	close(s.done)
+}
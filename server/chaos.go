@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var bandwidthByteCnt = prometheus.NewCounterVec(
+	prometheus.CounterOpts{Name: "bandwidth_bytes_total", Help: "Total bytes transferred, by direction and path"},
+	[]string{"direction", "path"},
+)
+
+func init() {
+	prometheus.MustRegister(bandwidthByteCnt)
+}
+
+// ChaosConfig holds the runtime-tunable failure-injection settings. It's
+// re-configurable at any time via POST /admin/chaos so operators can flip
+// conditions without restarting the server.
+type ChaosConfig struct {
+	FailRate      float64       `json:"fail_rate"`     // probability of a 5xx before any bytes are written
+	DropMidRate   float64       `json:"drop_mid_rate"` // probability of closing the connection mid-body
+	Latency       time.Duration `json:"latency"`       // fixed delay before the first byte
+	LatencyJitter time.Duration `json:"latency_jitter"`
+	ThrottleBps   int64         `json:"throttle_bps"` // 0 disables throttling
+}
+
+// chaosState guards the active ChaosConfig behind a mutex so admin updates
+// are safe to apply concurrently with in-flight requests.
+type chaosState struct {
+	mu  sync.RWMutex
+	cfg ChaosConfig
+}
+
+var chaos = &chaosState{}
+
+func (c *chaosState) Get() ChaosConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cfg
+}
+
+func (c *chaosState) Set(cfg ChaosConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+}
+
+// adminToken gates POST /admin/chaos; set via the -admin-token flag. An
+// empty token disables the endpoint entirely.
+var adminToken string
+
+// adminChaosHandler lets operators update the chaos config at runtime:
+//
+//	POST /admin/chaos
+//	Authorization: Bearer <admin-token>
+//	{"fail_rate": 0.1, "drop_mid_rate": 0, "latency": 50000000, "throttle_bps": 0}
+//
+// latency and latency_jitter are given in nanoseconds (50000000 is 50ms).
+// The body replaces the whole config; omitted fields are reset to zero.
+func adminChaosHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if adminToken == "" {
+		http.Error(w, "admin endpoint disabled", http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+adminToken {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var cfg ChaosConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	chaos.Set(cfg)
+	log.Printf("chaos config updated: %+v", cfg)
+	w.WriteHeader(http.StatusOK)
+}
+
+// applyPreResponseChaos sleeps for the configured latency (plus jitter) and
+// then, with probability cfg.FailRate, writes a 5xx and returns true so the
+// caller can return early without sending any real payload bytes.
+func applyPreResponseChaos(w http.ResponseWriter, path string) (fault string, aborted bool) {
+	cfg := chaos.Get()
+
+	if cfg.Latency > 0 || cfg.LatencyJitter > 0 {
+		d := cfg.Latency
+		if cfg.LatencyJitter > 0 {
+			d += time.Duration(rand.Int63n(int64(cfg.LatencyJitter)))
+		}
+		time.Sleep(d)
+	}
+
+	if cfg.FailRate > 0 && rand.Float64() < cfg.FailRate {
+		http.Error(w, "chaos: injected failure", http.StatusServiceUnavailable)
+		return "fail", true
+	}
+	return "", false
+}
+
+// shouldDropMid decides, per the current DropMidRate, whether this response
+// should be cut off partway through, and if so at what fraction of the body.
+func shouldDropMid() (fraction float64, drop bool) {
+	cfg := chaos.Get()
+	if cfg.DropMidRate > 0 && rand.Float64() < cfg.DropMidRate {
+		return rand.Float64(), true
+	}
+	return 0, false
+}
+
+// hijackAndDrop hijacks the connection, lets write send whatever it chooses
+// to the raw buffered writer, and then closes the underlying connection without a proper HTTP termination,
+// simulating a mid-transfer network drop. It requires the ResponseWriter to
+// support http.Hijacker.
+func hijackAndDrop(w http.ResponseWriter, write func(io.Writer) error) error {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return fmt.Errorf("chaos: ResponseWriter does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return fmt.Errorf("chaos: hijack failed: %w", err)
+	}
+	defer conn.Close()
+
+	if err := write(rw); err != nil {
+		return err
+	}
+	rw.Flush()
+	// Close the raw connection immediately instead of finishing the chunked/
+	// content-length framing, simulating a dropped connection.
+	return conn.Close()
+}
+
+// hijackAndDropRead is hijackAndDrop's read-side counterpart: it hijacks the
+// connection, lets read consume bytes from the hijacked buffered reader
+// (which already holds any bytes net/http read ahead while parsing the
+// request), then closes the raw connection without ever writing a response,
+// simulating the client's upload getting cut off mid-stream.
+func hijackAndDropRead(w http.ResponseWriter, read func(io.Reader) (int64, error)) (int64, error) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return 0, fmt.Errorf("chaos: ResponseWriter does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return 0, fmt.Errorf("chaos: hijack failed: %w", err)
+	}
+	defer conn.Close()
+	return read(rw.Reader)
+}
+
+// throttledWriter rate-limits writes to roughly bps bytes/sec using a token
+// bucket refilled every 10ms.
+type throttledWriter struct {
+	w    io.Writer
+	bps  int64
+	mu   sync.Mutex
+	toks int64
+	stop chan struct{}
+}
+
+func newThrottledWriter(w io.Writer, bps int64) *throttledWriter {
+	tw := &throttledWriter{w: w, bps: bps, toks: bps / 100, stop: make(chan struct{})}
+	go tw.refill()
+	return tw
+}
+
+func (tw *throttledWriter) refill() {
+	perTick := tw.bps / 100 // bps/100 bytes every 10ms
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			tw.mu.Lock()
+			tw.toks += perTick
+			if tw.toks > tw.bps {
+				tw.toks = tw.bps // cap the bucket to one second's worth
+			}
+			tw.mu.Unlock()
+		case <-tw.stop:
+			return
+		}
+	}
+}
+
+// Close stops the refill goroutine. Callers must call it once done writing.
+func (tw *throttledWriter) Close() {
+	close(tw.stop)
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		tw.mu.Lock()
+		avail := tw.toks
+		tw.mu.Unlock()
+		if avail <= 0 {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		}
+
+		chunk := int64(len(p) - written)
+		if chunk > avail {
+			chunk = avail
+		}
+		n, err := tw.w.Write(p[written : written+int(chunk)])
+		written += n
+		tw.mu.Lock()
+		tw.toks -= int64(n)
+		tw.mu.Unlock()
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// throttledReader is throttledWriter's read-side counterpart: it rate-limits
+// reads to roughly bps bytes/sec using the same token-bucket scheme, so
+// -throttle-bps can slow down /upload bodies, not just /download responses.
+type throttledReader struct {
+	r    io.Reader
+	bps  int64
+	mu   sync.Mutex
+	toks int64
+	stop chan struct{}
+}
+
+func newThrottledReader(r io.Reader, bps int64) *throttledReader {
+	tr := &throttledReader{r: r, bps: bps, toks: bps / 100, stop: make(chan struct{})}
+	go tr.refill()
+	return tr
+}
+
+func (tr *throttledReader) refill() {
+	perTick := tr.bps / 100 // bps/100 bytes every 10ms
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			tr.mu.Lock()
+			tr.toks += perTick
+			if tr.toks > tr.bps {
+				tr.toks = tr.bps // cap the bucket to one second's worth
+			}
+			tr.mu.Unlock()
+		case <-tr.stop:
+			return
+		}
+	}
+}
+
+// Close stops the refill goroutine. Callers must call it once done reading.
+func (tr *throttledReader) Close() {
+	close(tr.stop)
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	for {
+		tr.mu.Lock()
+		avail := tr.toks
+		tr.mu.Unlock()
+		if avail > 0 {
+			max := int64(len(p))
+			if max > avail {
+				max = avail
+			}
+			n, err := tr.r.Read(p[:max])
+			tr.mu.Lock()
+			tr.toks -= int64(n)
+			tr.mu.Unlock()
+			return n, err
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// countingReader wraps a request body, tallying bytes read for the
+// bandwidth logger. r is what Read pulls from (the raw body, or a
+// throttledReader wrapping it); closer releases the underlying connection
+// regardless of what r is.
+type countingReader struct {
+	r      io.Reader
+	closer io.Closer
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error { return c.closer.Close() }
+
+// countingResponseWriter wraps an http.ResponseWriter, tallying bytes
+// written for the bandwidth logger. It implements http.Hijacker so chaos's
+// drop-mid-stream path keeps working through the wrapper.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return c.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// bandwidthEntry is one line of the JSONL bandwidth log.
+type bandwidthEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	BytesIn    int64     `json:"bytes_in"`
+	BytesOut   int64     `json:"bytes_out"`
+	Fault      string    `json:"fault,omitempty"`
+}
+
+// bandwidthLogger accumulates per-(path,remote addr) byte counts and flushes
+// one JSONL line per key every second.
+type bandwidthLogger struct {
+	mu  sync.Mutex
+	agg map[string]*bandwidthEntry
+	out io.Writer
+}
+
+func newBandwidthLogger(out io.Writer) *bandwidthLogger {
+	return &bandwidthLogger{agg: make(map[string]*bandwidthEntry), out: out}
+}
+
+func (b *bandwidthLogger) record(path, remoteAddr string, bytesIn, bytesOut int64, fault string) {
+	key := remoteAddr + "|" + path
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.agg[key]
+	if !ok {
+		e = &bandwidthEntry{Path: path, RemoteAddr: remoteAddr}
+		b.agg[key] = e
+	}
+	e.BytesIn += bytesIn
+	e.BytesOut += bytesOut
+	if fault != "" {
+		e.Fault = fault
+	}
+}
+
+// run flushes accumulated bandwidth stats once a second until ctx-like stop
+// is requested by closing done.
+func (b *bandwidthLogger) run(done <-chan struct{}) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (b *bandwidthLogger) flush() {
+	b.mu.Lock()
+	entries := b.agg
+	b.agg = make(map[string]*bandwidthEntry)
+	b.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, e := range entries {
+		e.Timestamp = now
+		line, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintln(b.out, string(line))
+	}
+}
+
+var bwLogger = newBandwidthLogger(os.Stdout)
+
+// recordBandwidth updates both the JSONL aggregator and the Prometheus
+// counters for a completed request.
+func recordBandwidth(path, remoteAddr string, bytesIn, bytesOut int64, fault string) {
+	bwLogger.record(path, remoteAddr, bytesIn, bytesOut, fault)
+	if bytesIn > 0 {
+		bandwidthByteCnt.WithLabelValues("in", path).Add(float64(bytesIn))
+	}
+	if bytesOut > 0 {
+		bandwidthByteCnt.WithLabelValues("out", path).Add(float64(bytesOut))
+	}
+}
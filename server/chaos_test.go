@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+// withChaos sets the global chaos config for the duration of a test and
+// restores whatever was there before, since chaos is process-wide state
+// shared with the live handlers.
+func withChaos(t *testing.T, cfg ChaosConfig) {
+	t.Helper()
+	prev := chaos.Get()
+	chaos.Set(cfg)
+	t.Cleanup(func() { chaos.Set(prev) })
+}
+
+func TestApplyPreResponseChaosNeverFiresAtZeroRate(t *testing.T) {
+	withChaos(t, ChaosConfig{FailRate: 0})
+	w := httptest.NewRecorder()
+	fault, aborted := applyPreResponseChaos(w, "/download")
+	if aborted || fault != "" {
+		t.Errorf("applyPreResponseChaos with FailRate=0 = (%q, %v), want (\"\", false)", fault, aborted)
+	}
+}
+
+func TestApplyPreResponseChaosAlwaysFiresAtFullRate(t *testing.T) {
+	// rand.Float64() returns [0,1), so FailRate=1 makes `< cfg.FailRate`
+	// always true; this avoids needing to mock math/rand for determinism.
+	withChaos(t, ChaosConfig{FailRate: 1})
+	w := httptest.NewRecorder()
+	fault, aborted := applyPreResponseChaos(w, "/download")
+	if !aborted || fault != "fail" {
+		t.Errorf("applyPreResponseChaos with FailRate=1 = (%q, %v), want (\"fail\", true)", fault, aborted)
+	}
+	if w.Code != 503 {
+		t.Errorf("response status = %d, want 503", w.Code)
+	}
+}
+
+func TestShouldDropMidNeverFiresAtZeroRate(t *testing.T) {
+	withChaos(t, ChaosConfig{DropMidRate: 0})
+	if _, drop := shouldDropMid(); drop {
+		t.Error("shouldDropMid with DropMidRate=0 returned drop=true, want false")
+	}
+}
+
+func TestShouldDropMidAlwaysFiresAtFullRate(t *testing.T) {
+	withChaos(t, ChaosConfig{DropMidRate: 1})
+	if _, drop := shouldDropMid(); !drop {
+		t.Error("shouldDropMid with DropMidRate=1 returned drop=false, want true")
+	}
+}
+
+// TestThrottledWriterRespectsTokenBudget constructs a throttledWriter
+// directly (bypassing newThrottledWriter's refill goroutine, which runs on a
+// 10ms ticker unsuited to deterministic testing) with a token balance exactly
+// matching the payload size, so Write should drain it in one pass with no
+// refill needed.
+func TestThrottledWriterRespectsTokenBudget(t *testing.T) {
+	var dst bytes.Buffer
+	tw := &throttledWriter{w: &dst, bps: 1000, toks: 10}
+	payload := bytes.Repeat([]byte{'x'}, 10)
+
+	n, err := tw.Write(payload)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if n != len(payload) || dst.Len() != len(payload) {
+		t.Errorf("Write wrote %d bytes (dst has %d), want %d", n, dst.Len(), len(payload))
+	}
+}
+
+// TestThrottledWriterBlocksPastBudget checks that a write larger than the
+// available tokens doesn't complete until the refill goroutine tops the
+// bucket back up.
+func TestThrottledWriterBlocksPastBudget(t *testing.T) {
+	var dst bytes.Buffer
+	tw := newThrottledWriter(&dst, 1000) // ~10 tokens/10ms tick
+	defer tw.Close()
+
+	payload := bytes.Repeat([]byte{'x'}, 100)
+	done := make(chan struct{})
+	go func() {
+		tw.Write(payload)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Write returned before enough tokens could have refilled")
+	default:
+	}
+	<-done
+	if dst.Len() != len(payload) {
+		t.Errorf("throttledWriter eventually wrote %d bytes, want %d", dst.Len(), len(payload))
+	}
+}
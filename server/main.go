@@ -55,6 +55,26 @@ func pingHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("pong"))
 }
 
+// writePayload streams totalSize bytes to w by repeating the pre-generated
+// payloadChunk, returning the number of bytes actually written (which may be
+// less than totalSize if w returns an error, e.g. a chaos-induced drop).
+func writePayload(w io.Writer, totalSize int) (int64, error) {
+	var sent int64
+	chunkSize := len(payloadChunk)
+	for int(sent) < totalSize {
+		n := totalSize - int(sent)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		nw, err := w.Write(payloadChunk[:n])
+		sent += int64(nw)
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
 func downloadHandler(w http.ResponseWriter, r *http.Request) {
 	sizeParam := r.URL.Query().Get("size")
 	var totalSize int
@@ -70,38 +90,109 @@ func downloadHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if fault, aborted := applyPreResponseChaos(w, r.URL.Path); aborted {
+		recordBandwidth(r.URL.Path, r.RemoteAddr, 0, 0, fault)
+		return
+	}
+
+	cfg := chaos.Get()
+
+	if fraction, drop := shouldDropMid(); drop {
+		dropAt := int(float64(totalSize) * fraction)
+		hijackErr := hijackAndDrop(w, func(raw io.Writer) error {
+			header := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n", totalSize)
+			if _, err := io.WriteString(raw, header); err != nil {
+				return err
+			}
+			writer := raw
+			if cfg.ThrottleBps > 0 {
+				tw := newThrottledWriter(raw, cfg.ThrottleBps)
+				defer tw.Close()
+				writer = tw
+			}
+			_, err := writePayload(writer, dropAt)
+			return err
+		})
+		if hijackErr != nil {
+			log.Printf("chaos: drop-mid for /download: %v", hijackErr)
+		}
+		recordBandwidth(r.URL.Path, r.RemoteAddr, 0, int64(dropAt), "drop-mid")
+		log.Printf("Served /download size=%d bytes (chaos: dropped mid-stream at %d)", totalSize, dropAt)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Length", strconv.Itoa(totalSize))
 
-	// Stream by repeating the pre-generated chunk
-	var sent int
-	chunkSize := len(payloadChunk)
-	for sent < totalSize {
-		n := totalSize - sent
-		if n > chunkSize {
-			n = chunkSize
-		}
-		if _, err := w.Write(payloadChunk[:n]); err != nil {
-			log.Printf("Error writing chunk: %v", err)
-			return
-		}
-		sent += n
+	cw := &countingResponseWriter{ResponseWriter: w}
+	var writer io.Writer = cw
+	if cfg.ThrottleBps > 0 {
+		tw := newThrottledWriter(cw, cfg.ThrottleBps)
+		defer tw.Close()
+		writer = tw
+	}
+
+	sent, err := writePayload(writer, totalSize)
+	if err != nil {
+		log.Printf("Error writing chunk: %v", err)
 	}
-	downloadByteCnt.Add(float64(totalSize))
+	downloadByteCnt.Add(float64(sent))
+	recordBandwidth(r.URL.Path, r.RemoteAddr, 0, sent, "")
 	log.Printf("Served /download size=%d bytes", totalSize)
 }
 
 func uploadHandler(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-	bytesRead, err := io.Copy(io.Discard, r.Body)
+	if fault, aborted := applyPreResponseChaos(w, r.URL.Path); aborted {
+		recordBandwidth(r.URL.Path, r.RemoteAddr, 0, 0, fault)
+		return
+	}
+
+	cfg := chaos.Get()
+
+	if fraction, drop := shouldDropMid(); drop {
+		total := r.ContentLength
+		if total <= 0 {
+			total = int64(defaultDownloadSize)
+		}
+		dropAt := int64(float64(total) * fraction)
+
+		n, hijackErr := hijackAndDropRead(w, func(raw io.Reader) (int64, error) {
+			reader := raw
+			if cfg.ThrottleBps > 0 {
+				tr := newThrottledReader(raw, cfg.ThrottleBps)
+				defer tr.Close()
+				reader = tr
+			}
+			return io.CopyN(io.Discard, reader, dropAt)
+		})
+		if hijackErr != nil {
+			log.Printf("chaos: drop-mid for /upload: %v", hijackErr)
+		}
+		recordBandwidth(r.URL.Path, r.RemoteAddr, n, 0, "drop-mid")
+		log.Printf("Handled /upload: chaos dropped mid-stream after %d bytes", n)
+		return
+	}
+
+	var reader io.Reader = r.Body
+	if cfg.ThrottleBps > 0 {
+		tr := newThrottledReader(r.Body, cfg.ThrottleBps)
+		defer tr.Close()
+		reader = tr
+	}
+	cr := &countingReader{r: reader, closer: r.Body}
+	defer cr.Close()
+
+	bytesRead, err := io.Copy(io.Discard, cr)
 	if err != nil {
 		log.Printf("Error reading upload body: %v", err)
 		http.Error(w, "error reading body", http.StatusInternalServerError)
+		recordBandwidth(r.URL.Path, r.RemoteAddr, cr.n, 0, "")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(fmt.Sprintf("received %d bytes", bytesRead)))
 	uploadByteCnt.Add(float64(bytesRead))
+	recordBandwidth(r.URL.Path, r.RemoteAddr, bytesRead, 0, "")
 	log.Printf("Handled /upload: %d bytes received", bytesRead)
 }
 
@@ -113,9 +204,22 @@ func main() {
 	chunkSize := flag.Int("chunk-size", 64*1024, "size of each data chunk in bytes")
 	randomize := flag.Bool("random", false, "fill download chunks with random data")
 	defaultSize := flag.Int("default-size", 10*1024*1024, "default download size in bytes if not specified")
+	failRate := flag.Float64("fail-rate", 0, "probability (0-1) of returning a 5xx before any bytes are sent")
+	dropMidRate := flag.Float64("drop-mid-rate", 0, "probability (0-1) of dropping the connection partway through a download or upload")
+	latency := flag.Duration("latency", 0, "fixed delay injected before the first response byte")
+	latencyJitter := flag.Duration("latency-jitter", 0, "additional random delay (0..jitter) on top of -latency")
+	throttleBps := flag.Int64("throttle-bps", 0, "rate-limit download and upload bodies to this many bytes/sec (0 disables)")
+	flag.StringVar(&adminToken, "admin-token", "", "bearer token required to POST /admin/chaos (empty disables the endpoint)")
 	flag.Parse()
 
 	defaultDownloadSize = *defaultSize
+	chaos.Set(ChaosConfig{
+		FailRate:      *failRate,
+		DropMidRate:   *dropMidRate,
+		Latency:       *latency,
+		LatencyJitter: *latencyJitter,
+		ThrottleBps:   *throttleBps,
+	})
 
 	// Prepare payload chunk once
 	payloadChunk = make([]byte, *chunkSize)
@@ -133,9 +237,14 @@ func main() {
 	mux.HandleFunc("/download", downloadHandler)
 	mux.HandleFunc("/upload", uploadHandler)
 	mux.HandleFunc("/healthz", healthHandler)
+	mux.HandleFunc("/admin/chaos", adminChaosHandler)
 	mux.Handle("/metrics", promhttp.Handler())
 	loggedMux := loggingMiddleware(mux)
 
+	bwLoggerDone := make(chan struct{})
+	defer close(bwLoggerDone)
+	go bwLogger.run(bwLoggerDone)
+
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", *port),
 		Handler:      loggedMux,
@@ -145,6 +254,8 @@ func main() {
 	}
 
 	log.Printf("Starting speedtest backend: port=%d, chunk-size=%d, random=%v, default-size=%d", *port, *chunkSize, *randomize, *defaultSize)
+	log.Printf("Chaos config: fail-rate=%.2f, drop-mid-rate=%.2f, latency=%v, latency-jitter=%v, throttle-bps=%d, admin-endpoint=%v",
+		*failRate, *dropMidRate, *latency, *latencyJitter, *throttleBps, adminToken != "")
 
 	// run server in goroutine for graceful shutdown
 	errChan := make(chan error, 1)